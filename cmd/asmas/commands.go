@@ -0,0 +1,331 @@
+package main
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+	"github.com/valyala/fasthttp"
+
+	"github.com/MindHunter86/asmas/internal/audit"
+	"github.com/MindHunter86/asmas/internal/auth"
+)
+
+func commandsInitialization(log *zerolog.Logger) []*cli.Command {
+	return []*cli.Command{
+		{
+			Name:  "audit",
+			Usage: "audit log helpers",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "verify",
+					Usage:     "verify hash chain of audit files given in chronological order",
+					ArgsUsage: "<audit.log.1> [audit.log.2 ...]",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name: "anchor",
+							Usage: "trusted hash of the record preceding the first given one, e.g. the last hash of " +
+								"the previous verification; the chain must start from the genesis if empty",
+						},
+					},
+					Action: commandAuditVerify,
+				},
+			},
+		},
+		{
+			Name:      "sign-request",
+			Usage:     "sign v1 api request with the shared token; print the signed url or fetch the pem",
+			ArgsUsage: " ",
+			Flags: []cli.Flag{
+				&cli.StringFlag{
+					Name:     "server",
+					Usage:    "asmas base url, e.g. https://asmas.example.com",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "name",
+					Usage: "certificate name (authorization list entry); it's optional for whoami signed by the token",
+				},
+				&cli.StringFlag{
+					Name:     "hostname",
+					Usage:    "hostname of the requesting client",
+					Required: true,
+				},
+				&cli.StringFlag{
+					Name:  "ip",
+					Usage: "client ip as the server sees it (see http-realip-header); empty if the server can't see it",
+				},
+				&cli.StringFlag{
+					Name:    "token",
+					Usage:   "shared hmac token, see auth-sign-token",
+					EnvVars: []string{"ASMAS_SIGN_TOKEN"},
+				},
+				&cli.StringFlag{
+					Name:    "key",
+					Usage:   "ed25519 private key pem of the host; it's used instead of the token",
+					Aliases: []string{"k"},
+					EnvVars: []string{"ASMAS_SIGN_KEY_FILE"},
+				},
+				&cli.StringFlag{
+					Name:  "type",
+					Usage: "request type - public, private, info or whoami",
+					Value: "public",
+				},
+				&cli.BoolFlag{
+					Name:  "fetch",
+					Usage: "perform the request and write decoded response instead of printing the signed url",
+				},
+				&cli.StringFlag{
+					Name:    "output",
+					Usage:   "fetched pem destination; stdout is used if empty",
+					Aliases: []string{"o"},
+				},
+				&cli.DurationFlag{
+					Name:  "timeout",
+					Usage: "fetch request timeout",
+					Value: 10 * time.Second,
+				},
+			},
+			Action: commandSignRequest,
+		},
+		{
+			Name:      "keygen",
+			Usage:     "generate ed25519 private key of the host and print its public key for the hostname in the config public_keys",
+			ArgsUsage: "<host.key>",
+			Action:    commandKeygen,
+		},
+		{
+			Name:  "config",
+			Usage: "authorization config helpers",
+			Subcommands: []*cli.Command{
+				{
+					Name:      "validate",
+					Usage:     "verify and validate local config.yaml or config.yaml.asc like the service does",
+					ArgsUsage: "<config.yaml|config.yaml.asc>",
+					Action: func(c *cli.Context) error {
+						return commandConfigValidate(c, log)
+					},
+				},
+				{
+					Name:      "sign",
+					Usage:     "validate and clearsign local config.yaml with the secret key",
+					ArgsUsage: "<config.yaml> | --append <config.yaml.asc>",
+					Flags: []cli.Flag{
+						&cli.StringFlag{
+							Name:     "key",
+							Usage:    "armored secret key file",
+							Aliases:  []string{"k"},
+							Required: true,
+						},
+						&cli.StringFlag{
+							Name:    "passphrase-file",
+							Usage:   "file with the secret key passphrase; ASMAS_SIGN_PASSPHRASE env is used if empty",
+							EnvVars: []string{"ASMAS_SIGN_PASSPHRASE_FILE"},
+						},
+						&cli.StringFlag{
+							Name:    "output",
+							Usage:   "signed config destination; stdout is used if empty",
+							Aliases: []string{"o"},
+						},
+						&cli.BoolFlag{
+							Name:    "append",
+							Usage:   "append the signature to the clearsigned config, see auth-signers-threshold",
+							Aliases: []string{"a"},
+						},
+					},
+					Action: func(c *cli.Context) error {
+						return commandConfigSign(c, log)
+					},
+				},
+			},
+		},
+	}
+}
+
+func commandAuditVerify(c *cli.Context) (e error) {
+	if c.NArg() == 0 {
+		return errors.New("at least one audit file argument is required")
+	}
+
+	var records int
+	if records, e = audit.VerifyFiles(c.Args().Slice(), c.String("anchor"), os.Stdout); e != nil {
+		return fmt.Errorf("audit chain verification failed after %d records, %s", records, e.Error())
+	}
+
+	return
+}
+
+func commandSignRequest(c *cli.Context) (e error) {
+	query := url.Values{}
+	query.Set("hostname", c.String("hostname"))
+
+	if c.String("name") == "" && (c.String("type") != "whoami" || c.String("key") != "") {
+		return errors.New("certificate name is required for the request")
+	}
+
+	var path string
+	switch c.String("type") {
+	case "public", "private", "info":
+		path = "/v1/certificates/" + c.String("name") + "/" + c.String("type")
+	case "whoami":
+		path = "/v1/whoami"
+
+		// the entry name is required for ed25519 public keys lookup only
+		if c.String("key") != "" {
+			query.Set("name", c.String("name"))
+		}
+	default:
+		return errors.New("unknown request type " + c.String("type"))
+	}
+
+	switch {
+	case c.String("key") != "":
+		var payload []byte
+		if payload, e = os.ReadFile(c.String("key")); e != nil {
+			return
+		}
+
+		var key ed25519.PrivateKey
+		if key, e = auth.ReadRequestKey(payload); e != nil {
+			return
+		}
+
+		query.Set("signature", auth.SignRequestWithKey(key, c.String("ip"), path, c.String("hostname")))
+	case c.String("token") != "":
+		var sign string
+		if sign, e = auth.SignRequest(c.String("token"), c.String("ip"), path, c.String("hostname")); e != nil {
+			return
+		}
+
+		query.Set("sign", sign)
+	default:
+		return errors.New("token or key is required for signing")
+	}
+
+	signed := strings.TrimSuffix(c.String("server"), "/") + path + "?" + query.Encode()
+	if !c.Bool("fetch") {
+		fmt.Println(signed)
+		return
+	}
+
+	req, rsp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(rsp)
+
+	req.SetRequestURI(signed)
+	if e = (&fasthttp.Client{}).DoTimeout(req, rsp, c.Duration("timeout")); e != nil {
+		return
+	}
+
+	if rsp.StatusCode() != fasthttp.StatusOK {
+		return fmt.Errorf("server responded with status %d, %s", rsp.StatusCode(), bytes.TrimSpace(rsp.Body()))
+	}
+
+	// pems are base64 encoded by the server, info and whoami are plain json
+	payload := rsp.Body()
+	if c.String("type") == "public" || c.String("type") == "private" {
+		if payload, e = base64.StdEncoding.DecodeString(string(payload)); e != nil {
+			return errors.New("could not decode server response, " + e.Error())
+		}
+	}
+
+	if c.String("output") == "" {
+		_, e = os.Stdout.Write(payload)
+		return
+	}
+
+	return os.WriteFile(c.String("output"), payload, 0600)
+}
+
+func commandKeygen(c *cli.Context) (e error) {
+	if c.NArg() != 1 {
+		return errors.New("private key file argument is required")
+	}
+
+	// existing keys are never overwritten
+	var fd *os.File
+	if fd, e = os.OpenFile(c.Args().First(), os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600); e != nil {
+		return
+	}
+	defer fd.Close()
+
+	var public string
+	if public, e = auth.GenerateRequestKey(fd); e != nil {
+		return
+	}
+
+	fmt.Println(public)
+	return
+}
+
+func commandConfigValidate(c *cli.Context, log *zerolog.Logger) (e error) {
+	if c.NArg() != 1 {
+		return errors.New("config file argument is required")
+	}
+
+	var payload []byte
+	if payload, e = os.ReadFile(c.Args().First()); e != nil {
+		return
+	}
+
+	authlist, errs := auth.ValidateConfig(c, log, payload)
+	if len(errs) != 0 {
+		for _, err := range errs {
+			fmt.Fprintf(os.Stderr, "%s: %s\n", c.Args().First(), err.Error())
+		}
+
+		return fmt.Errorf("config %s is invalid, %d error(s) found", c.Args().First(), len(errs))
+	}
+
+	fmt.Printf("%s: OK, %d authorization entries\n", c.Args().First(), len(authlist.AuthorizationList))
+	return
+}
+
+func commandConfigSign(c *cli.Context, log *zerolog.Logger) (e error) {
+	if c.NArg() != 1 {
+		return errors.New("config file argument is required")
+	}
+
+	var payload, keyring []byte
+	if payload, e = os.ReadFile(c.Args().First()); e != nil {
+		return
+	}
+
+	if keyring, e = os.ReadFile(c.String("key")); e != nil {
+		return
+	}
+
+	passphrase := []byte(os.Getenv("ASMAS_SIGN_PASSPHRASE"))
+	if c.String("passphrase-file") != "" {
+		if passphrase, e = os.ReadFile(c.String("passphrase-file")); e != nil {
+			return
+		}
+		passphrase = bytes.TrimRight(passphrase, "\r\n")
+	}
+
+	var w io.Writer = os.Stdout
+	if c.String("output") != "" {
+		var fd *os.File
+		if fd, e = os.OpenFile(c.String("output"), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0644); e != nil {
+			return
+		}
+		defer fd.Close()
+
+		w = fd
+	}
+
+	if c.Bool("append") {
+		return auth.AppendConfigSignature(c, log, payload, bytes.NewReader(keyring), passphrase, w)
+	}
+
+	return auth.SignConfig(c, log, payload, bytes.NewReader(keyring), passphrase, w)
+}
@@ -111,25 +111,93 @@ func flagsInitialization(expertmode bool) []cli.Flag {
 			Hidden:   expertmode,
 		},
 
+		&cli.StringFlag{
+			Name:     "http-internal-secret",
+			Category: "HTTP server settings",
+			Usage:    "define static secret in x-internal-secret header for internal api access; internal api is disabled if value is empty",
+			EnvVars:  []string{"INTERNAL_SECRET"},
+		},
+
+		// brute-force protection settings
+		&cli.DurationFlag{
+			Name:     "lockout-window",
+			Category: "Brute-force protection settings",
+			Usage:    "sliding window for failed request signatures counting",
+			Value:    10 * time.Minute,
+		},
+		&cli.IntFlag{
+			Name:     "lockout-ip-threshold",
+			Category: "Brute-force protection settings",
+			Usage: "failed signatures from one client ip in the window before lockout; behind a reverse proxy " +
+				"define http-trusted-proxies, otherwise all clients share the proxy ip; 0 - disabled",
+		},
+		&cli.IntFlag{
+			Name:     "lockout-hostname-threshold",
+			Category: "Brute-force protection settings",
+			Usage: "failed signatures for one hostname in the window before lockout; it's alerting only, " +
+				"the hostname is unauthenticated and valid signatures are never blocked; 0 - disabled",
+			Value: 20,
+		},
+		&cli.DurationFlag{
+			Name:     "lockout-duration",
+			Category: "Brute-force protection settings",
+			Usage:    "locked out clients receive 429 with retry-after for this duration",
+			Value:    15 * time.Minute,
+		},
+		&cli.StringSliceFlag{
+			Name:     "lockout-allowlist",
+			Category: "Brute-force protection settings",
+			Usage:    "client ips and networks which are never locked out, e.g. 10.0.0.0/8",
+		},
+		&cli.IntFlag{
+			Name:     "lockout-max-counters",
+			Category: "Brute-force protection settings",
+			Usage:    "limit of tracked client ips and, separately, hostnames; least recently failed counters are evicted; 0 - unlimited",
+			Value:    100000,
+		},
+
 		// auth service settings
 		&cli.StringFlag{
 			Name:     "auth-sign-token",
 			Category: "Auth service settings",
+			Usage:    "shared hmac token for v1 api requests; empty value disables hmac, hosts must use ed25519 public_keys then",
 			Value:    "changemeplease12345",
 		},
+		&cli.StringFlag{
+			Name:     "auth-signers-keyring",
+			Category: "Auth service settings",
+			Usage:    "armored public keyring of trusted config signers; built-in keyring is used if value is empty",
+			EnvVars:  []string{"AUTH_SIGNERS_KEYRING"},
+		},
+		&cli.StringFlag{
+			Name:     "auth-signers-policy",
+			Category: "Auth service settings",
+			Usage: "yaml file restricting trusted signers to certificate names, i.e. " +
+				"signers: [{fingerprint: ABCD..., names: ['*.a.example.com']}]; signers are unrestricted if value is empty",
+			EnvVars: []string{"AUTH_SIGNERS_POLICY"},
+		},
+		&cli.IntFlag{
+			Name:     "auth-signers-threshold",
+			Category: "Auth service settings",
+			Usage:    "minimal number of distinct trusted signers of the config; signatures are appended with config sign --append",
+			Value:    1,
+		},
 		&cli.StringFlag{
 			Name:     "auth-github-repo",
 			Category: "Auth service settings",
+			Usage:    "also used by gitlab and gitea config sources",
 			Value:    "MindHunter86/asmas-test",
 		},
 		&cli.StringFlag{
 			Name:     "auth-github-path",
 			Category: "Auth service settings",
+			Usage:    "config file path or, for github source only, directory with signed *.yaml.asc files merged in one list; also used by gitlab, gitea and git config sources",
 			Value:    "config.yaml.asc",
 		},
 		&cli.StringFlag{
 			Name:     "auth-github-branch",
 			Category: "Auth service settings",
+			Usage:    "also used by gitlab, gitea and git config sources",
 			Value:    "master",
 		},
 		&cli.DurationFlag{
@@ -142,14 +210,143 @@ func flagsInitialization(expertmode bool) []cli.Flag {
 			Category: "Auth service settings",
 			Value:    1 * time.Minute,
 		},
+		&cli.StringFlag{
+			Name:     "auth-webhook-secret",
+			Category: "Auth service settings",
+			Usage:    "secret of github webhook for POST /hooks/github; webhook is disabled if value is empty",
+			EnvVars:  []string{"AUTH_WEBHOOK_SECRET"},
+		},
+		&cli.DurationFlag{
+			Name:     "auth-webhook-coalesce-delay",
+			Category: "Auth service settings",
+			Usage:    "authorization list update is delayed after webhook, so bursts of pushes cause one update",
+			Value:    5 * time.Second,
+			Hidden:   expertmode,
+		},
+		&cli.DurationFlag{
+			Name:     "auth-grant-expiry-warning",
+			Category: "Auth service settings",
+			Usage:    "time-bound grants expiring within this duration are reported in logs; 0 - disabled",
+			Value:    7 * 24 * time.Hour,
+		},
+		&cli.IntFlag{
+			Name:     "auth-diff-history",
+			Category: "Auth service settings",
+			Usage:    "count of the last authorization list diffs available from the internal api; 0 - disabled",
+			Value:    20,
+		},
+		&cli.StringFlag{
+			Name:     "auth-state-dir",
+			Category: "Auth service settings",
+			Usage:    "directory for the last-known-good signed config; it's used on startup if config source is unavailable",
+			EnvVars:  []string{"AUTH_STATE_DIR"},
+		},
+		&cli.BoolFlag{
+			Name:     "auth-config-serials",
+			Category: "Auth service settings",
+			Usage: "refuse replayed configs; configs must carry increasing serial, files of the config " +
+				"directory must carry their names in file key; add them to configs before enabling",
+		},
+		&cli.DurationFlag{
+			Name:     "auth-config-max-age",
+			Category: "Auth service settings",
+			Usage:    "configs issued earlier than this duration are reported, issued_at is required; 0 - disabled",
+		},
+		&cli.BoolFlag{
+			Name:     "auth-config-max-age-deny",
+			Category: "Auth service settings",
+			Usage:    "deny private key requests if the applied config is older than auth-config-max-age",
+		},
+		&cli.DurationFlag{
+			Name:     "auth-state-max-age",
+			Category: "Auth service settings",
+			Usage:    "last-known-good config older than this duration is not used; 0 - unlimited",
+			Value:    72 * time.Hour,
+		},
+
+		// auth config source settings
+		&cli.StringFlag{
+			Name:     "auth-source",
+			Category: "Config source settings",
+			Usage:    "where the signed config is loaded from; github, gitlab, gitea, url, file, git are possible",
+			Value:    "github",
+			EnvVars:  []string{"AUTH_SOURCE"},
+		},
+		&cli.StringFlag{
+			Name:     "auth-source-url",
+			Category: "Config source settings",
+			Usage:    "url of the signed config for url source; format - https://example.com/config.yaml.asc",
+		},
+		&cli.StringFlag{
+			Name:     "auth-source-forge-url",
+			Category: "Config source settings",
+			Usage:    "gitlab or gitea base url; format - https://gitlab.com",
+		},
+		&cli.StringFlag{
+			Name:     "auth-source-forge-token",
+			Category: "Config source settings",
+			Usage:    "access token for gitlab, gitea and url sources (optional)",
+			EnvVars:  []string{"AUTH_SOURCE_FORGE_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:     "auth-source-file",
+			Category: "Config source settings",
+			Usage:    "path to the signed config for file source",
+		},
+		&cli.StringFlag{
+			Name:     "auth-source-git-dir",
+			Category: "Config source settings",
+			Usage:    "path to the local git checkout for git source",
+		},
+		&cli.BoolFlag{
+			Name:     "auth-source-git-pull",
+			Category: "Config source settings",
+			Usage:    "run git pull --ff-only in the local checkout before each config reading",
+		},
 
 		// github http client settings
+		&cli.StringFlag{
+			Name:     "github-token",
+			Category: "Github client settings",
+			Usage:    "fine-grained personal access token with contents read permission; it's required for private repositories",
+			EnvVars:  []string{"GITHUB_TOKEN"},
+		},
+		&cli.StringFlag{
+			Name:     "github-token-file",
+			Category: "Github client settings",
+			Usage:    "file with the personal access token; it overrides github-token",
+			EnvVars:  []string{"GITHUB_TOKEN_FILE"},
+		},
+		&cli.Int64Flag{
+			Name:     "github-app-id",
+			Category: "Github client settings",
+			Usage:    "github app id for installation tokens authentication; it has priority over the personal access token",
+			EnvVars:  []string{"GITHUB_APP_ID"},
+		},
+		&cli.Int64Flag{
+			Name:     "github-app-installation-id",
+			Category: "Github client settings",
+			Usage:    "github app installation id in the config repository owner account",
+			EnvVars:  []string{"GITHUB_APP_INSTALLATION_ID"},
+		},
+		&cli.StringFlag{
+			Name:     "github-app-private-key-file",
+			Category: "Github client settings",
+			Usage:    "github app private key pem",
+			EnvVars:  []string{"GITHUB_APP_PRIVATE_KEY_FILE"},
+		},
 		&cli.StringFlag{
 			Name:     "github-api-addr",
 			Category: "Github client settings",
 			Value:    "api.github.com:443",
 			Hidden:   expertmode,
 		},
+		&cli.StringFlag{
+			Name:     "github-api-url",
+			Category: "Github client settings",
+			Usage:    "github api base url with the path prefix, e.g. https://ghe.corp/api/v3 or http://127.0.0.1:8080 for a local mirror; https://<github-api-addr> if empty",
+			EnvVars:  []string{"GITHUB_API_URL"},
+		},
 		&cli.StringFlag{
 			Name:     "github-api-version",
 			Category: "Github client settings",
@@ -162,12 +359,76 @@ func flagsInitialization(expertmode bool) []cli.Flag {
 			Category: "Github client settings",
 			Hidden:   expertmode,
 		},
+		&cli.StringFlag{
+			Name:     "github-ca-file",
+			Category: "Github client settings",
+			Usage:    "pem bundle of additional CAs trusted by config source http clients, system roots are kept",
+		},
+		&cli.StringFlag{
+			Name:     "github-client-cert",
+			Category: "Github client settings",
+			Usage:    "client certificate pem for config source http clients, i.e. for enterprise proxies",
+		},
+		&cli.StringFlag{
+			Name:     "github-client-key",
+			Category: "Github client settings",
+			Usage:    "client certificate private key pem",
+		},
+		&cli.StringFlag{
+			Name:     "github-proxy",
+			Category: "Github client settings",
+			Usage: "proxy url for config source http clients, http://host:port for HTTP CONNECT " +
+				"or socks5://host:port proxies; HTTPS_PROXY and HTTP_PROXY are used if empty",
+			EnvVars: []string{"ASMAS_GITHUB_PROXY"},
+		},
+		&cli.StringFlag{
+			Name:     "github-proxy-auth",
+			Category: "Github client settings",
+			Usage:    "proxy credentials in user:password format, they override ones given in the proxy url",
+			EnvVars:  []string{"ASMAS_GITHUB_PROXY_AUTH"},
+		},
+		&cli.BoolFlag{
+			Name:     "github-proxy-from-env",
+			Category: "Github client settings",
+			Usage:    "use HTTPS_PROXY and HTTP_PROXY environment variables if github-proxy is empty",
+			Value:    true,
+		},
+		&cli.StringFlag{
+			Name:     "github-no-proxy",
+			Category: "Github client settings",
+			Usage:    "comma-separated hosts, domains and networks which are dialed directly; loopback is never proxied",
+			EnvVars:  []string{"NO_PROXY", "no_proxy"},
+		},
 		&cli.IntFlag{
 			Name:     "github-max-conns",
 			Category: "Github client settings",
 			Value:    32,
 			Hidden:   expertmode,
 		},
+		&cli.IntFlag{
+			Name:     "github-max-config-size",
+			Category: "Github client settings",
+			Usage:    "size limit in bytes of config files; files over 1 MB are fetched from the git blobs api",
+			Value:    16 * 1024 * 1024,
+		},
+		&cli.IntFlag{
+			Name:     "github-retry-attempts",
+			Category: "Github client settings",
+			Usage:    "attempts count for transient and rate limited github api errors in one pull",
+			Value:    3,
+		},
+		&cli.DurationFlag{
+			Name:     "github-retry-backoff",
+			Category: "Github client settings",
+			Usage:    "base of the exponential backoff with jitter between attempts",
+			Value:    time.Second,
+		},
+		&cli.DurationFlag{
+			Name:     "github-retry-max-backoff",
+			Category: "Github client settings",
+			Usage:    "backoff limit; longer Retry-After delays are waited by the next pull instead",
+			Value:    30 * time.Second,
+		},
 		&cli.DurationFlag{
 			Name:     "github-timeout-read",
 			Category: "Github client settings",
@@ -205,6 +466,26 @@ func flagsInitialization(expertmode bool) []cli.Flag {
 			Hidden:   expertmode,
 		},
 
+		// audit settings
+		&cli.StringFlag{
+			Name:     "audit-file",
+			Category: "Audit settings",
+			Usage:    "hash-chained audit log of authn, authz decisions and served pems; audit is disabled if value is empty",
+			EnvVars:  []string{"AUDIT_FILE"},
+		},
+		&cli.Int64Flag{
+			Name:     "audit-max-size",
+			Category: "Audit settings",
+			Usage:    "audit file is rotated after this size in megabytes; 0 - disabled",
+			Value:    100,
+		},
+		&cli.IntFlag{
+			Name:     "audit-max-backups",
+			Category: "Audit settings",
+			Usage:    "rotated audit files count limit; 0 - unlimited",
+			Value:    10,
+		},
+
 		// Certbot settings
 		&cli.BoolFlag{
 			Name:     "certbot-args-reuse-key",
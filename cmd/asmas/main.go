@@ -64,6 +64,7 @@ func main() {
 	app.HideHelpCommand = true
 	app.Flags = flagsInitialization(
 		!strings.Contains(strings.Join(os.Args, " "), "--expert-mode"))
+	app.Commands = commandsInitialization(&log)
 
 	app.Action = func(c *cli.Context) (e error) {
 		var lvl zerolog.Level
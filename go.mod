@@ -1,6 +1,6 @@
 module github.com/MindHunter86/asmas
 
-go 1.19
+go 1.21
 
 require (
 	github.com/ProtonMail/go-crypto v1.0.0
@@ -9,6 +9,7 @@ require (
 	github.com/rs/zerolog v1.33.0
 	github.com/urfave/cli/v2 v2.27.5
 	github.com/valyala/fasthttp v1.57.0
+	golang.org/x/net v0.30.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -29,4 +30,5 @@ require (
 	github.com/xrash/smetrics v0.0.0-20240521201337-686a1a2994c1 // indirect
 	golang.org/x/crypto v0.28.0 // indirect
 	golang.org/x/sys v0.26.0 // indirect
+	golang.org/x/text v0.19.0 // indirect
 )
@@ -0,0 +1,299 @@
+package audit
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/MindHunter86/asmas/internal/utils"
+	"github.com/mailru/easyjson"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	mbyteSize        int64 = 1024 * 1024
+	rotatedTimestamp       = "20060102T150405.000000000"
+	maxRecordSize          = 64 * 1024
+)
+
+// Auditor writes hash-chained records of authn, authz decisions and served pems
+type Auditor struct {
+	path       string
+	maxsize    int64
+	maxbackups int
+
+	mu   sync.Mutex
+	fd   *os.File
+	size int64
+	prev string
+
+	log  *zerolog.Logger
+	done func() <-chan struct{}
+}
+
+func NewAuditor(c context.Context, cc *cli.Context) (_ *Auditor, e error) {
+	auditor := &Auditor{
+		path:       cc.String("audit-file"),
+		maxsize:    cc.Int64("audit-max-size") * mbyteSize,
+		maxbackups: cc.Int("audit-max-backups"),
+
+		prev: genesisHash,
+
+		log:  c.Value(utils.CKeyLogger).(*zerolog.Logger),
+		done: c.Done,
+	}
+
+	if auditor.path == "" {
+		auditor.log.Warn().Msg("audit file is not defined, audit records will not be written")
+		return auditor, e
+	}
+
+	// chain is continued from the last record of the existing file
+	if auditor.prev, e = lastRecordHash(auditor.path); e != nil {
+		return
+	}
+
+	return auditor, auditor.open()
+}
+
+func (m *Auditor) Bootstrap() {
+	<-m.done()
+
+	actionWithLock(&m.mu, func() {
+		if m.fd == nil {
+			return
+		}
+
+		if e := m.fd.Close(); e != nil {
+			m.log.Error().Msg("an error occurred while closing audit file, " + e.Error())
+		}
+		m.fd = nil
+	})
+}
+
+func (m *Auditor) IsEnabled() bool {
+	return m.path != ""
+}
+
+// Write chains and writes the record; write errors are logged, requests are not declined
+func (m *Auditor) Write(record *Record) {
+	if !m.IsEnabled() {
+		return
+	}
+
+	record.Time = time.Now().UTC()
+
+	actionWithLock(&m.mu, func() {
+		if m.fd == nil {
+			m.log.Error().Msg("BUG! audit file is closed, the record is lost")
+			return
+		}
+
+		var e error
+		record.Prev = m.prev
+		if record.Hash, e = record.computeHash(); e != nil {
+			m.log.Error().Msg("an error occurred while hashing audit record, " + e.Error())
+			return
+		}
+
+		var payload []byte
+		if payload, e = easyjson.Marshal(record); e != nil {
+			m.log.Error().Msg("an error occurred while encoding audit record, " + e.Error())
+			return
+		}
+		payload = append(payload, '\n')
+
+		if m.maxsize != 0 && m.size+int64(len(payload)) > m.maxsize {
+			if e = m.rotate(); e != nil {
+				m.log.Error().Msg("an error occurred while rotating audit file, " + e.Error())
+			}
+		}
+
+		var n int
+		n, e = m.fd.Write(payload)
+		m.size += int64(n)
+
+		if e != nil {
+			m.log.Error().Msg("an error occurred while writing audit record, " + e.Error())
+			return
+		}
+
+		m.prev = record.Hash
+	})
+}
+
+// VerifyFiles checks the hash chain of the given audit files in chronological order;
+// the chain must start from the anchor hash or, if it's empty, from the genesis
+func VerifyFiles(paths []string, anchor string, w io.Writer) (records int, e error) {
+	prev := genesisHash
+	if anchor != "" {
+		prev = anchor
+	}
+
+	for _, path := range paths {
+		var fd *os.File
+		if fd, e = os.Open(path); e != nil {
+			return
+		}
+
+		var count int
+		count, prev, e = verifyChain(fd, prev)
+		fd.Close()
+
+		if e != nil {
+			return records, fmt.Errorf("%s: %s", path, e.Error())
+		}
+
+		records += count
+		fmt.Fprintf(w, "%s: OK, %d records\n", path, count)
+	}
+
+	fmt.Fprintf(w, "last record hash %s\n", prev)
+	return
+}
+
+//
+//
+//
+
+func (m *Auditor) open() (e error) {
+	if m.fd, e = os.OpenFile(m.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600); e != nil {
+		return
+	}
+
+	var fdinfo os.FileInfo
+	if fdinfo, e = m.fd.Stat(); e != nil {
+		return
+	}
+
+	m.size = fdinfo.Size()
+	return
+}
+
+func (m *Auditor) rotate() (e error) {
+	if e = m.fd.Close(); e != nil {
+		return
+	}
+
+	var rotated string
+	if rotated, e = m.rotatedPath(); e != nil {
+		return
+	}
+
+	if e = os.Rename(m.path, rotated); e != nil {
+		return
+	}
+
+	if e = m.open(); e != nil {
+		return
+	}
+
+	if m.maxbackups == 0 {
+		return
+	}
+
+	var backups []string
+	if backups, e = filepath.Glob(m.path + ".*"); e != nil {
+		return
+	}
+	sort.Strings(backups)
+
+	for len(backups) > m.maxbackups {
+		if e = os.Remove(backups[0]); e != nil {
+			return
+		}
+		backups = backups[1:]
+	}
+
+	return
+}
+
+// rotatedPath returns the unused backup name; names are sorted chronologically,
+// the counter suffix is only added for rotations in the same nanosecond
+func (m *Auditor) rotatedPath() (_ string, e error) {
+	rotated := m.path + "." + time.Now().UTC().Format(rotatedTimestamp)
+
+	for i := 0; ; i++ {
+		path := rotated
+		if i != 0 {
+			path = fmt.Sprintf("%s-%d", rotated, i)
+		}
+
+		if _, e = os.Lstat(path); errors.Is(e, os.ErrNotExist) {
+			return path, nil
+		} else if e != nil {
+			return
+		}
+	}
+}
+
+func lastRecordHash(path string) (_ string, e error) {
+	var fd *os.File
+	if fd, e = os.Open(path); errors.Is(e, os.ErrNotExist) {
+		return genesisHash, nil
+	} else if e != nil {
+		return
+	}
+	defer fd.Close()
+
+	var prev string
+	if _, prev, e = verifyChain(fd, ""); e != nil {
+		return "", errors.New("existing audit file is corrupted, " + e.Error())
+	}
+
+	if prev == "" {
+		prev = genesisHash
+	}
+
+	return prev, e
+}
+
+// verifyChain returns records count and the last hash; empty prev means
+// the first record is trusted as the chain start (i.e. older files are rotated out)
+func verifyChain(r io.Reader, prev string) (records int, _ string, e error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 4096), maxRecordSize)
+
+	for line := 1; scanner.Scan(); line++ {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+
+		record := &Record{}
+		if e = easyjson.Unmarshal(scanner.Bytes(), record); e != nil {
+			return records, prev, fmt.Errorf("line %d: could not decode record, %s", line, e.Error())
+		}
+
+		if prev != "" && record.Prev != prev {
+			return records, prev, fmt.Errorf("line %d: chain is broken, previous record hash %s is expected", line, prev)
+		}
+
+		var hash string
+		if hash, e = record.computeHash(); e != nil {
+			return
+		} else if hash != record.Hash {
+			return records, prev, fmt.Errorf("line %d: record hash mismatch, record has been modified", line)
+		}
+
+		prev = record.Hash
+		records++
+	}
+
+	return records, prev, scanner.Err()
+}
+
+func actionWithLock(mu *sync.Mutex, action func()) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	action()
+}
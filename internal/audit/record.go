@@ -0,0 +1,58 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+
+	"github.com/mailru/easyjson"
+)
+
+const (
+	EVENT_AUTHN = "authn"
+	EVENT_AUTHZ = "authz"
+	EVENT_PEM   = "pem"
+
+	METHOD_HMAC    = "hmac"
+	METHOD_ED25519 = "ed25519"
+
+	RESULT_ALLOWED = "allowed"
+	RESULT_SERVED  = "served"
+)
+
+// hash of the virtual record before the first one
+var genesisHash = hex.EncodeToString(make([]byte, sha256.Size))
+
+//easyjson:json
+type Record struct {
+	Time      time.Time `json:"time"`
+	RequestId string    `json:"request_id,omitempty"`
+	Event     string    `json:"event"`
+	Method    string    `json:"method,omitempty"`
+	Result    string    `json:"result"`
+	Hostname  string    `json:"hostname,omitempty"`
+	ClientIP  string    `json:"client_ip,omitempty"`
+	Entry     string    `json:"entry,omitempty"`
+	PemType   string    `json:"pem_type,omitempty"`
+	Serial    string    `json:"serial,omitempty"`
+
+	// hash chain, each record contains the hash of the previous one;
+	// the record hash is sha256 of the record json without the hash field
+	Prev string `json:"prev"`
+	Hash string `json:"hash,omitempty"`
+}
+
+func (m *Record) computeHash() (_ string, e error) {
+	hash := m.Hash
+	defer func() { m.Hash = hash }()
+
+	m.Hash = ""
+
+	var payload []byte
+	if payload, e = easyjson.Marshal(m); e != nil {
+		return
+	}
+
+	sum := sha256.Sum256(payload)
+	return hex.EncodeToString(sum[:]), e
+}
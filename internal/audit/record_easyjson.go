@@ -0,0 +1,164 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package audit
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson15d5d517DecodeGithubComMindHunter86AsmasInternalAudit(in *jlexer.Lexer, out *Record) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "time":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Time).UnmarshalJSON(data))
+			}
+		case "request_id":
+			out.RequestId = string(in.String())
+		case "event":
+			out.Event = string(in.String())
+		case "method":
+			out.Method = string(in.String())
+		case "result":
+			out.Result = string(in.String())
+		case "hostname":
+			out.Hostname = string(in.String())
+		case "client_ip":
+			out.ClientIP = string(in.String())
+		case "entry":
+			out.Entry = string(in.String())
+		case "pem_type":
+			out.PemType = string(in.String())
+		case "serial":
+			out.Serial = string(in.String())
+		case "prev":
+			out.Prev = string(in.String())
+		case "hash":
+			out.Hash = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson15d5d517EncodeGithubComMindHunter86AsmasInternalAudit(out *jwriter.Writer, in Record) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"time\":"
+		out.RawString(prefix[1:])
+		out.Raw((in.Time).MarshalJSON())
+	}
+	if in.RequestId != "" {
+		const prefix string = ",\"request_id\":"
+		out.RawString(prefix)
+		out.String(string(in.RequestId))
+	}
+	{
+		const prefix string = ",\"event\":"
+		out.RawString(prefix)
+		out.String(string(in.Event))
+	}
+	if in.Method != "" {
+		const prefix string = ",\"method\":"
+		out.RawString(prefix)
+		out.String(string(in.Method))
+	}
+	{
+		const prefix string = ",\"result\":"
+		out.RawString(prefix)
+		out.String(string(in.Result))
+	}
+	if in.Hostname != "" {
+		const prefix string = ",\"hostname\":"
+		out.RawString(prefix)
+		out.String(string(in.Hostname))
+	}
+	if in.ClientIP != "" {
+		const prefix string = ",\"client_ip\":"
+		out.RawString(prefix)
+		out.String(string(in.ClientIP))
+	}
+	if in.Entry != "" {
+		const prefix string = ",\"entry\":"
+		out.RawString(prefix)
+		out.String(string(in.Entry))
+	}
+	if in.PemType != "" {
+		const prefix string = ",\"pem_type\":"
+		out.RawString(prefix)
+		out.String(string(in.PemType))
+	}
+	if in.Serial != "" {
+		const prefix string = ",\"serial\":"
+		out.RawString(prefix)
+		out.String(string(in.Serial))
+	}
+	{
+		const prefix string = ",\"prev\":"
+		out.RawString(prefix)
+		out.String(string(in.Prev))
+	}
+	if in.Hash != "" {
+		const prefix string = ",\"hash\":"
+		out.RawString(prefix)
+		out.String(string(in.Hash))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v Record) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson15d5d517EncodeGithubComMindHunter86AsmasInternalAudit(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v Record) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson15d5d517EncodeGithubComMindHunter86AsmasInternalAudit(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *Record) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson15d5d517DecodeGithubComMindHunter86AsmasInternalAudit(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *Record) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson15d5d517DecodeGithubComMindHunter86AsmasInternalAudit(l, v)
+}
@@ -0,0 +1,170 @@
+package auth
+
+import (
+	"errors"
+	"net/netip"
+	"strings"
+	"time"
+)
+
+type Scope string
+
+const (
+	SCOPE_PUBLIC  Scope = "public"
+	SCOPE_PRIVATE Scope = "private"
+	SCOPE_INFO    Scope = "info"
+)
+
+// entries without scopes keep access to everything for compatibility with old configs
+var defaultScopes = []Scope{SCOPE_PUBLIC, SCOPE_PRIVATE, SCOPE_INFO}
+
+type AuthzResult uint8
+
+const (
+	AUTHZ_ALLOWED AuthzResult = iota
+	AUTHZ_UNKNOWN_NAME
+	AUTHZ_HOSTNAME_MISMATCH
+	AUTHZ_NETWORK_MISMATCH
+	AUTHZ_SCOPE_DENIED
+	AUTHZ_GRANT_INACTIVE
+	AUTHZ_CONFIG_EXPIRED
+)
+
+func (m AuthzResult) String() string {
+	switch m {
+	case AUTHZ_ALLOWED:
+		return "allowed"
+	case AUTHZ_UNKNOWN_NAME:
+		return "unknown certificate name"
+	case AUTHZ_HOSTNAME_MISMATCH:
+		return "hostname mismatch"
+	case AUTHZ_NETWORK_MISMATCH:
+		return "client network mismatch"
+	case AUTHZ_SCOPE_DENIED:
+		return "scope is not granted"
+	case AUTHZ_GRANT_INACTIVE:
+		return "grant is expired or not active yet"
+	case AUTHZ_CONFIG_EXPIRED:
+		return "authorization config is older than allowed"
+	default:
+		return "undefined"
+	}
+}
+
+// Authorize checks the hostname, the client ip and the scope of the request for the given certificate name
+func (m *AuthService) Authorize(name, hostname, ip string, scope Scope) (_ AuthzResult, e error) {
+	if !m.isApiReady() {
+		return AUTHZ_UNKNOWN_NAME, errors.New("auth service api is not ready yet")
+	}
+
+	if actionReturbableWithRLock[bool](&m.mu, m.isStateTooOld) {
+		return AUTHZ_UNKNOWN_NAME, errors.New("last-known-good authorization config is too old, refusing to use it")
+	}
+
+	var addr netip.Addr
+	if addr, e = netip.ParseAddr(ip); e != nil {
+		return AUTHZ_NETWORK_MISMATCH, errors.New("could not parse client ip, " + e.Error())
+	}
+
+	return actionReturbableWithRLock[AuthzResult](&m.mu, func() AuthzResult {
+		var auth *YamlAuthorization
+		if auth = m.authlist.authorizationByFqdn(name); auth == nil {
+			return AUTHZ_UNKNOWN_NAME
+		}
+
+		if result := auth.matchFqdn(hostname, time.Now()); result != AUTHZ_ALLOWED {
+			return result
+		}
+
+		if !auth.isAuthorizedAddr(addr) {
+			return AUTHZ_NETWORK_MISMATCH
+		}
+
+		if !auth.isAuthorizedScope(scope) {
+			return AUTHZ_SCOPE_DENIED
+		}
+
+		// private keys are not served by outdated configs, a revocation may be withheld
+		if scope == SCOPE_PRIVATE && m.configagedeny && m.isConfigTooOld() {
+			return AUTHZ_CONFIG_EXPIRED
+		}
+
+		return AUTHZ_ALLOWED
+	}), e
+}
+
+//
+//
+//
+
+func (m *YamlAuthorization) isAuthorizedAddr(addr netip.Addr) bool {
+	if len(m.networks) == 0 {
+		return true
+	}
+
+	addr = addr.Unmap()
+	for _, network := range m.networks {
+		if network.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *YamlAuthorization) isAuthorizedScope(scope Scope) bool {
+	_, ok := m.scopes[scope]
+	return ok
+}
+
+func parseScopes(scopes []string) (_ map[Scope]struct{}, e error) {
+	parsed := make(map[Scope]struct{}, len(defaultScopes))
+
+	if len(scopes) == 0 {
+		for _, scope := range defaultScopes {
+			parsed[scope] = struct{}{}
+		}
+
+		return parsed, e
+	}
+
+	for _, scope := range scopes {
+		switch Scope(scope) {
+		case SCOPE_PUBLIC, SCOPE_PRIVATE, SCOPE_INFO:
+			parsed[Scope(scope)] = struct{}{}
+		default:
+			return nil, errors.New("unknown scope " + scope)
+		}
+	}
+
+	return parsed, e
+}
+
+// parseAllowedNetworks accepts CIDRs and bare addresses
+func parseAllowedNetworks(networks []string) (prefixes []netip.Prefix, e error) {
+	for _, network := range networks {
+		network = strings.TrimSpace(network)
+
+		var prefix netip.Prefix
+		if strings.Contains(network, "/") {
+			if prefix, e = netip.ParsePrefix(network); e != nil {
+				return
+			}
+		} else {
+			var addr netip.Addr
+			if addr, e = netip.ParseAddr(network); e != nil {
+				return
+			}
+
+			prefix = netip.PrefixFrom(addr, addr.BitLen())
+		}
+
+		if prefix.Addr().Is4In6() && prefix.Bits() >= 96 {
+			prefix = netip.PrefixFrom(prefix.Addr().Unmap(), prefix.Bits()-96)
+		}
+
+		prefixes = append(prefixes, prefix.Masked())
+	}
+
+	return
+}
@@ -0,0 +1,219 @@
+package auth
+
+import (
+	"bytes"
+	"crypto"
+	"errors"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
+	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	"github.com/ProtonMail/go-crypto/openpgp/packet"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+)
+
+// helpers for cli subcommands, they share verification code with the running service
+
+var (
+	clearsignHeader = []byte("-----BEGIN PGP SIGNED MESSAGE-----")
+	signatureHeader = []byte("-----BEGIN PGP SIGNATURE-----")
+)
+
+func newOfflineAuthService(cc *cli.Context, log *zerolog.Logger) *AuthService {
+	return &AuthService{
+		keyring:    cc.String("auth-signers-keyring"),
+		policyfile: cc.String("auth-signers-policy"),
+		threshold:  cc.Int("auth-signers-threshold"),
+		strictyaml: true,
+		pgpconfig: &packet.Config{
+			DefaultHash: crypto.SHA512,
+		},
+
+		log: log,
+	}
+}
+
+// ValidateConfig runs the same checks as the service does for received configs;
+// the signature is verified only if the given payload is clearsigned
+func ValidateConfig(cc *cli.Context, log *zerolog.Logger, payload []byte) (_ *YamlConfig, errs []error) {
+	m := newOfflineAuthService(cc, log)
+
+	var e error
+	var signers openpgp.EntityList
+	if bytes.HasPrefix(bytes.TrimSpace(payload), clearsignHeader) {
+		if m.signers, e = m.loadConfigSigners(); e != nil {
+			return nil, []error{errors.New("could not load signers keyring, " + e.Error())}
+		}
+
+		if m.policy, e = m.loadSignersPolicy(); e != nil {
+			return nil, []error{errors.New("could not load signers policy, " + e.Error())}
+		}
+
+		if payload, signers, e = m.validateConfigSign(payload); e != nil {
+			return nil, []error{errors.New("could not verify config signature, " + e.Error())}
+		}
+	} else {
+		m.log.Warn().Msg("given config is not clearsigned, signature verification is skipped")
+	}
+
+	var authlist *YamlConfig
+	if authlist, e = m.unmarshalYamlConfig(payload); e != nil {
+		return nil, []error{e}
+	}
+
+	if len(signers) != 0 {
+		authlist.setSigners(signersFingerprints(signers))
+	}
+
+	if errs = m.validateAuthorizationList(authlist); len(errs) != 0 {
+		return nil, errs
+	}
+
+	return authlist, nil
+}
+
+// SignConfig validates the given yaml and writes it clearsigned with the first
+// signing key found in the armored secret keyring; clearsigned configs are refused,
+// signing them again produces the nested message, see AppendConfigSignature
+func SignConfig(cc *cli.Context, log *zerolog.Logger, payload []byte, keyring io.Reader, passphrase []byte, w io.Writer) (e error) {
+	if bytes.HasPrefix(bytes.TrimSpace(payload), clearsignHeader) {
+		return errors.New("config is already clearsigned, use --append to add the signature")
+	}
+
+	if _, errs := ValidateConfig(cc, log, payload); len(errs) != 0 {
+		for _, err := range errs {
+			log.Error().Msg(err.Error())
+		}
+
+		return errors.New("refusing to sign invalid config")
+	}
+
+	var entities openpgp.EntityList
+	if entities, e = openpgp.ReadArmoredKeyRing(keyring); e != nil {
+		return
+	}
+
+	var key *packet.PrivateKey
+	if _, key, e = signingKeyFromEntities(entities, passphrase); e != nil {
+		return
+	}
+
+	var plaintext io.WriteCloser
+	if plaintext, e = clearsign.Encode(w, key, &packet.Config{DefaultHash: crypto.SHA512}); e != nil {
+		return
+	}
+
+	if _, e = plaintext.Write(payload); e != nil {
+		plaintext.Close()
+		return
+	}
+
+	return plaintext.Close()
+}
+
+// AppendConfigSignature adds the signature of the first signing key found in the armored secret
+// keyring to the clearsigned config; it's used for collecting signatures of auth-signers-threshold
+func AppendConfigSignature(cc *cli.Context, log *zerolog.Logger, payload []byte, keyring io.Reader, passphrase []byte, w io.Writer) (e error) {
+	var signblock *clearsign.Block
+	if signblock, _ = clearsign.Decode(payload); signblock == nil {
+		return errors.New("could not decode PGP signed file, clear sign not found")
+	}
+
+	if _, errs := ValidateConfig(cc, log, signblock.Plaintext); len(errs) != 0 {
+		for _, err := range errs {
+			log.Error().Msg(err.Error())
+		}
+
+		return errors.New("refusing to sign invalid config")
+	}
+
+	// appended signatures must use the hash declared in the clearsign header
+	if hashes := strings.Join(signblock.Headers.Values("Hash"), ","); !strings.Contains(hashes, "SHA512") {
+		return errors.New("config is signed with unsupported hash " + hashes + ", SHA512 is expected")
+	}
+
+	var signature []byte
+	if signature, e = io.ReadAll(signblock.ArmoredSignature.Body); e != nil {
+		return
+	}
+
+	var entities openpgp.EntityList
+	if entities, e = openpgp.ReadArmoredKeyRing(keyring); e != nil {
+		return
+	}
+
+	var entity *openpgp.Entity
+	var key *packet.PrivateKey
+	if entity, key, e = signingKeyFromEntities(entities, passphrase); e != nil {
+		return
+	}
+
+	block := *signblock
+	block.ArmoredSignature = &armor.Block{Type: signblock.ArmoredSignature.Type, Body: bytes.NewReader(signature)}
+	if _, err := block.VerifySignature(openpgp.EntityList{entity}, nil); err == nil {
+		return errors.New("config is already signed by " + signerIdentity(entity))
+	}
+
+	buf := bytes.NewBuffer(signature)
+	config := &packet.Config{DefaultHash: crypto.SHA512, SigningKeyId: key.KeyId}
+	if e = openpgp.DetachSignText(buf, entity, bytes.NewReader(signblock.Bytes), config); e != nil {
+		return
+	}
+
+	// the signed text is dash-escaped, so the signature block is the first armor line
+	if _, e = w.Write(payload[:bytes.Index(payload, signatureHeader)]); e != nil {
+		return
+	}
+
+	var armored io.WriteCloser
+	if armored, e = armor.Encode(w, signblock.ArmoredSignature.Type, nil); e != nil {
+		return
+	}
+
+	if _, e = armored.Write(buf.Bytes()); e != nil {
+		armored.Close()
+		return
+	}
+
+	if e = armored.Close(); e != nil {
+		return
+	}
+
+	_, e = w.Write([]byte("\n"))
+	return
+}
+
+//
+//
+//
+
+func signingKeyFromEntities(entities openpgp.EntityList, passphrase []byte) (*openpgp.Entity, *packet.PrivateKey, error) {
+	for _, entity := range entities {
+		if entity.PrivateKey == nil {
+			continue
+		}
+
+		key, ok := entity.SigningKey(time.Now())
+		if !ok || key.PrivateKey == nil {
+			continue
+		}
+
+		if key.PrivateKey.Encrypted {
+			if len(passphrase) == 0 {
+				return nil, nil, errors.New("signing key is encrypted, passphrase is required")
+			}
+
+			if e := key.PrivateKey.Decrypt(passphrase); e != nil {
+				return nil, nil, errors.New("could not decrypt signing key, " + e.Error())
+			}
+		}
+
+		return entity, key.PrivateKey, nil
+	}
+
+	return nil, nil, errors.New("there is no usable signing key in the given keyring")
+}
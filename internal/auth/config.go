@@ -2,10 +2,12 @@ package auth
 
 import (
 	"bytes"
-	"regexp"
-	"strings"
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net/netip"
+	"time"
 
-	futils "github.com/gofiber/fiber/v2/utils"
 	"gopkg.in/yaml.v3"
 )
 
@@ -14,20 +16,81 @@ type (
 		Config *YamlConfig
 	}
 	YamlConfig struct {
+		// Serial must be increased with every change, IssuedAt is the signing time;
+		// both are used for refusing replayed and outdated configs. File is the name
+		// of the config directory file, it binds the signed content to the file
+		Serial   uint64    `yaml:",omitempty"`
+		IssuedAt time.Time `yaml:"issued_at,omitempty"`
+		File     string    `yaml:",omitempty"`
+
 		AuthorizationList []*YamlAuthorization `yaml:"authorization_list"`
 	}
 	YamlAuthorization struct {
-		Name    string
-		Domains string                  `yaml:",omitempty"`
-		Reload  map[string]*YamlService `yaml:",omitempty"`
+		Name            string
+		Domains         string                  `yaml:",omitempty"`
+		AllowedNetworks []string                `yaml:"allowed_networks,omitempty"`
+		Grants          []*YamlGrant            `yaml:",omitempty"`
+		Scopes          []string                `yaml:",omitempty"`
+		Reload          map[string]*YamlService `yaml:",omitempty"`
+		PublicKeys      map[string][]string     `yaml:"public_keys,omitempty"`
+
+		patterns []*domainPattern
+		networks []netip.Prefix
+		scopes   map[Scope]struct{}
+		keys     map[string][]ed25519.PublicKey
+		file     string
+		line     int
 
-		domregexp *regexp.Regexp
+		// fingerprints of the counted config signers, they are checked by signers policy
+		signers []string
 	}
 	YamlService struct {
 		Command []string `yaml:"cmd"`
 	}
+
+	// ConfigError is a validation error of the authorization list entry
+	ConfigError struct {
+		File string
+		Line int
+		Name string
+		Err  string
+	}
 )
 
+func (m *ConfigError) Error() string {
+	var file string
+	if m.File != "" {
+		file = m.File + ": "
+	}
+
+	if m.Line == 0 {
+		return fmt.Sprintf("%sentry %s: %s", file, m.Name, m.Err)
+	}
+
+	return fmt.Sprintf("%sline %d: entry %s: %s", file, m.Line, m.Name, m.Err)
+}
+
+func (m *YamlAuthorization) errorf(format string, args ...interface{}) error {
+	return &ConfigError{File: m.file, Line: m.line, Name: m.Name, Err: fmt.Sprintf(format, args...)}
+}
+
+// location returns the file and the line of the entry for validation messages
+func (m *YamlAuthorization) location() string {
+	if m.file == "" {
+		return fmt.Sprintf("line %d", m.line)
+	}
+
+	return fmt.Sprintf("%s line %d", m.file, m.line)
+}
+
+func (m *YamlConfig) setSigners(fingerprints []string) {
+	for _, authorization := range m.AuthorizationList {
+		if authorization != nil {
+			authorization.signers = fingerprints
+		}
+	}
+}
+
 func (m *YamlConfig) authorizationByFqdn(fqdn string) *YamlAuthorization {
 	for _, authorization := range m.AuthorizationList {
 		if authorization.Name == fqdn {
@@ -38,33 +101,90 @@ func (m *YamlConfig) authorizationByFqdn(fqdn string) *YamlAuthorization {
 	return nil
 }
 
-func (m *YamlAuthorization) isAuthorizedFqdn(fqdn string) bool {
-	if m.domregexp == nil {
-		domains := strings.Split(m.Domains, ",")
+// matchFqdn checks the hostname against permanent domains and time-bound grants
+func (m *YamlAuthorization) matchFqdn(fqdn string, now time.Time) AuthzResult {
+	fqdn = normalizeFqdn(fqdn)
 
-		for _, domain := range domains {
-			if domain == fqdn {
-				return true
-			}
+	for _, pattern := range m.patterns {
+		if pattern.match(fqdn) {
+			return AUTHZ_ALLOWED
+		}
+	}
+
+	result := AUTHZ_HOSTNAME_MISMATCH
+	for _, grant := range m.Grants {
+		if grant == nil || !grant.matchFqdn(fqdn) {
+			continue
+		}
+
+		if grant.isActive(now) {
+			return AUTHZ_ALLOWED
 		}
 
-		return false
+		result = AUTHZ_GRANT_INACTIVE
 	}
 
-	return m.domregexp.Match(futils.UnsafeBytes(fqdn))
+	return result
 }
 
 //
 //
 //
 
-func (*AuthService) unmarshalYamlConfig(payload []byte) (_ *YamlConfig, e error) {
+func (m *AuthService) unmarshalYamlConfig(payload []byte) (_ *YamlConfig, e error) {
 	config := &YamlRoot{}
 
+	// unknown keys are rejected by cli, a typo in the key name may change access rules silently;
+	// the service only warns about them, configs with extra keys are still applied
 	decoder := yaml.NewDecoder(bytes.NewBuffer(payload))
-	if e = decoder.Decode(config); e != nil {
+	decoder.KnownFields(true)
+	if e = decoder.Decode(config); e != nil && m.strictyaml {
+		return
+	} else if e != nil {
+		m.log.Warn().Msg("config contains unknown or invalid keys, unknown keys are ignored - " + e.Error())
+
+		config = &YamlRoot{}
+		if e = yaml.Unmarshal(payload, config); e != nil {
+			return
+		}
+	}
+
+	if config.Config == nil {
+		return nil, errors.New("config root key is not found in the given yaml")
+	}
+
+	// second pass is used for saving entries lines for validation errors
+	var root yaml.Node
+	if e = yaml.Unmarshal(payload, &root); e != nil {
 		return
 	}
 
+	entries := yamlMappingValue(yamlMappingValue(&root, "config"), "authorization_list")
+	if entries != nil && len(entries.Content) == len(config.Config.AuthorizationList) {
+		for i, entity := range config.Config.AuthorizationList {
+			if entity != nil {
+				entity.line = entries.Content[i].Line
+			}
+		}
+	}
+
 	return config.Config, e
 }
+
+func yamlMappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node != nil && node.Kind == yaml.DocumentNode && len(node.Content) != 0 {
+		node = node.Content[0]
+	}
+
+	if node == nil || node.Kind != yaml.MappingNode {
+		return nil
+	}
+
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+
+	return nil
+}
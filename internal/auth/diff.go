@@ -0,0 +1,195 @@
+package auth
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+type (
+	// ConfigDiff describes changes between the applied and the received authorization lists
+	ConfigDiff struct {
+		Time    time.Time    `json:"time"`
+		Source  string       `json:"source"`
+		Sha     string       `json:"sha"`
+		PrevSha string       `json:"prev_sha,omitempty"`
+		Signer  string       `json:"signer"`
+		Commit  string       `json:"commit,omitempty"`
+		Added   []string     `json:"added,omitempty"`
+		Removed []string     `json:"removed,omitempty"`
+		Changed []*EntryDiff `json:"changed,omitempty"`
+	}
+	EntryDiff struct {
+		Name   string       `json:"name"`
+		Fields []*FieldDiff `json:"fields"`
+	}
+	FieldDiff struct {
+		Field string `json:"field"`
+		Old   string `json:"old"`
+		New   string `json:"new"`
+	}
+)
+
+// ConfigDiffs returns the last authorization list diffs, the newest is first
+func (m *AuthService) ConfigDiffs() (diffs []*ConfigDiff) {
+	actionWithRLock(&m.mu, func() {
+		diffs = make([]*ConfigDiff, 0, len(m.diffs))
+		for i := len(m.diffs) - 1; i >= 0; i-- {
+			diffs = append(diffs, m.diffs[i])
+		}
+	})
+
+	return
+}
+
+//
+//
+//
+
+// reportConfigDiff logs and saves the diff; it must be called with the write lock held
+func (m *AuthService) reportConfigDiff(oldlist, newlist *YamlConfig, payload *ConfigPayload) {
+	diff := diffAuthorizationLists(oldlist, newlist)
+	diff.Time, diff.Sha, diff.PrevSha, diff.Signer = time.Now(), payload.Sha, m.appliedsha, payload.Signer
+	if m.source != nil {
+		diff.Source = m.source.String()
+	}
+	if payload.Commit != nil {
+		diff.Commit = payload.Commit.Sha
+	}
+
+	m.log.Info().Msgf("authorization config %s with hash %s signed by %s: %d added, %d removed, %d changed entries",
+		payload.Name, payload.Sha, payload.Signer, len(diff.Added), len(diff.Removed), len(diff.Changed))
+
+	// the initial load is reported with counters only
+	if oldlist == nil {
+		m.saveConfigDiff(diff)
+		return
+	}
+
+	for _, name := range diff.Added {
+		m.log.Info().Msgf("authorization config diff - entry %s has been added", name)
+	}
+	for _, name := range diff.Removed {
+		m.log.Info().Msgf("authorization config diff - entry %s has been removed", name)
+	}
+	for _, entry := range diff.Changed {
+		for _, field := range entry.Fields {
+			m.log.Info().Msgf("authorization config diff - entry %s: %s changed from %q to %q",
+				entry.Name, field.Field, field.Old, field.New)
+		}
+	}
+
+	m.saveConfigDiff(diff)
+}
+
+func (m *AuthService) saveConfigDiff(diff *ConfigDiff) {
+	if m.diffhistory <= 0 {
+		return
+	}
+
+	if m.diffs = append(m.diffs, diff); len(m.diffs) > m.diffhistory {
+		m.diffs = m.diffs[len(m.diffs)-m.diffhistory:]
+	}
+}
+
+// diffAuthorizationLists compares entries by name; nil oldlist means the initial load
+func diffAuthorizationLists(oldlist, newlist *YamlConfig) (diff *ConfigDiff) {
+	diff = &ConfigDiff{}
+
+	olds := make(map[string]*YamlAuthorization)
+	if oldlist != nil {
+		for _, entry := range oldlist.AuthorizationList {
+			olds[entry.Name] = entry
+		}
+	}
+
+	news := make(map[string]struct{}, len(newlist.AuthorizationList))
+	for _, entry := range newlist.AuthorizationList {
+		news[entry.Name] = struct{}{}
+
+		old, ok := olds[entry.Name]
+		if !ok {
+			diff.Added = append(diff.Added, entry.Name)
+			continue
+		}
+
+		if fields := diffAuthorizations(old, entry); len(fields) != 0 {
+			diff.Changed = append(diff.Changed, &EntryDiff{Name: entry.Name, Fields: fields})
+		}
+	}
+
+	for name := range olds {
+		if _, ok := news[name]; !ok {
+			diff.Removed = append(diff.Removed, name)
+		}
+	}
+	sort.Strings(diff.Removed)
+
+	return
+}
+
+func diffAuthorizations(oldentry, newentry *YamlAuthorization) (fields []*FieldDiff) {
+	compare := func(field, oldval, newval string) {
+		if oldval != newval {
+			fields = append(fields, &FieldDiff{Field: field, Old: oldval, New: newval})
+		}
+	}
+
+	compare("domains", oldentry.Domains, newentry.Domains)
+	compare("grants", formatGrants(oldentry.Grants), formatGrants(newentry.Grants))
+	compare("allowed_networks", strings.Join(oldentry.AllowedNetworks, ","), strings.Join(newentry.AllowedNetworks, ","))
+	compare("scopes", strings.Join(oldentry.Scopes, ","), strings.Join(newentry.Scopes, ","))
+	compare("public_keys", formatPublicKeys(oldentry.PublicKeys), formatPublicKeys(newentry.PublicKeys))
+	compare("reload", formatReload(oldentry.Reload), formatReload(newentry.Reload))
+
+	return
+}
+
+func formatPublicKeys(keys map[string][]string) string {
+	formatted := make([]string, 0, len(keys))
+
+	for hostname, hostkeys := range keys {
+		formatted = append(formatted, hostname+"="+strings.Join(hostkeys, "|"))
+	}
+	sort.Strings(formatted)
+
+	return strings.Join(formatted, ",")
+}
+
+func formatGrants(grants []*YamlGrant) string {
+	formatted := make([]string, 0, len(grants))
+
+	for _, grant := range grants {
+		var notbefore, notafter string
+		if !grant.NotBefore.IsZero() {
+			notbefore = grant.NotBefore.Format(time.RFC3339)
+		}
+		if !grant.NotAfter.IsZero() {
+			notafter = grant.NotAfter.Format(time.RFC3339)
+		}
+
+		formatted = append(formatted, grant.Domains+" ["+notbefore+" - "+notafter+"]")
+	}
+
+	return strings.Join(formatted, "; ")
+}
+
+func formatReload(services map[string]*YamlService) string {
+	names := make([]string, 0, len(services))
+	for name := range services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	formatted := make([]string, 0, len(names))
+	for _, name := range names {
+		var command []string
+		if services[name] != nil {
+			command = services[name].Command
+		}
+
+		formatted = append(formatted, name+": "+strings.Join(command, " "))
+	}
+
+	return strings.Join(formatted, "; ")
+}
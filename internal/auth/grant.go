@@ -0,0 +1,83 @@
+package auth
+
+import (
+	"errors"
+	"time"
+)
+
+// YamlGrant is a time-bound access for hostnames matched by Domains;
+// zero NotBefore or NotAfter means unbounded
+type YamlGrant struct {
+	Domains   string
+	NotBefore time.Time `yaml:"not_before,omitempty"`
+	NotAfter  time.Time `yaml:"not_after,omitempty"`
+
+	patterns []*domainPattern
+}
+
+func (m *YamlGrant) isActive(now time.Time) bool {
+	if !m.NotBefore.IsZero() && now.Before(m.NotBefore) {
+		return false
+	}
+
+	return m.NotAfter.IsZero() || now.Before(m.NotAfter)
+}
+
+func (m *YamlGrant) matchFqdn(fqdn string) bool {
+	for _, pattern := range m.patterns {
+		if pattern.match(fqdn) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *YamlGrant) parse() (e error) {
+	if m.Domains == "" {
+		return errors.New("grant domains are empty")
+	}
+
+	if !m.NotBefore.IsZero() && !m.NotAfter.IsZero() && !m.NotAfter.After(m.NotBefore) {
+		return errors.New("grant not_after must be later than not_before")
+	}
+
+	m.patterns, e = parseDomainPatterns(m.Domains)
+	return
+}
+
+//
+//
+//
+
+// checkExpiringGrants logs grants which are going to expire soon and updates expiration metrics
+func (m *AuthService) checkExpiringGrants() {
+	if m.grantwarning == 0 {
+		return
+	}
+
+	now := time.Now()
+	metricGrantExpiration.Reset()
+
+	actionWithRLock(&m.mu, func() {
+		if m.authlist == nil {
+			return
+		}
+
+		for _, entity := range m.authlist.AuthorizationList {
+			for _, grant := range entity.Grants {
+				if grant == nil || grant.NotAfter.IsZero() {
+					continue
+				}
+
+				left := grant.NotAfter.Sub(now)
+				metricGrantExpiration.Set(left.Seconds(), entity.Name, grant.Domains)
+
+				if left > 0 && left <= m.grantwarning {
+					m.log.Warn().Msgf("grant %s of entry %s expires in %s (at %s)",
+						grant.Domains, entity.Name, left.Round(time.Minute).String(), grant.NotAfter.Format(time.RFC3339))
+				}
+			}
+		}
+	})
+}
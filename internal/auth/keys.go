@@ -0,0 +1,138 @@
+package auth
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+)
+
+// hosts sign the request message (see RequestHMACMessage) with Ed25519 private keys;
+// the config carries base64 encoded raw public keys bound to hostnames of the entry,
+// the signature is base64url encoded
+
+const pemTypePrivateKey = "PRIVATE KEY"
+
+// VerifyRequestSignature checks the signature with public keys of the authorization entry
+// bound to the requested hostname, so a host key can't sign requests for other hostnames
+func (m *AuthService) VerifyRequestSignature(name, hostname string, message []byte, signature string) (ok bool, e error) {
+	if !m.isApiReady() {
+		return false, errors.New("auth service is not ready yet")
+	}
+
+	var sign []byte
+	if sign, e = base64.RawURLEncoding.DecodeString(signature); e != nil || len(sign) != ed25519.SignatureSize {
+		return false, nil
+	}
+
+	actionWithRLock(&m.mu, func() {
+		var entry *YamlAuthorization
+		if entry = m.authlist.authorizationByFqdn(name); entry == nil {
+			return
+		}
+
+		for _, key := range entry.keys[normalizeFqdn(hostname)] {
+			if ok = ed25519.Verify(key, message, sign); ok {
+				return
+			}
+		}
+	})
+
+	return ok, nil
+}
+
+// GenerateRequestKey writes PKCS #8 pem of the new private key and returns its public key for the config
+func GenerateRequestKey(w io.Writer) (_ string, e error) {
+	var public ed25519.PublicKey
+	var private ed25519.PrivateKey
+	if public, private, e = ed25519.GenerateKey(rand.Reader); e != nil {
+		return
+	}
+
+	var der []byte
+	if der, e = x509.MarshalPKCS8PrivateKey(private); e != nil {
+		return
+	}
+
+	if e = pem.Encode(w, &pem.Block{Type: pemTypePrivateKey, Bytes: der}); e != nil {
+		return
+	}
+
+	return base64.StdEncoding.EncodeToString(public), e
+}
+
+// ReadRequestKey parses PKCS #8 pem, e.g. generated by GenerateRequestKey or `openssl genpkey -algorithm ed25519`
+func ReadRequestKey(payload []byte) (_ ed25519.PrivateKey, e error) {
+	var block *pem.Block
+	if block, _ = pem.Decode(payload); block == nil || block.Type != pemTypePrivateKey {
+		return nil, errors.New("could not decode private key pem")
+	}
+
+	var key interface{}
+	if key, e = x509.ParsePKCS8PrivateKey(block.Bytes); e != nil {
+		return
+	}
+
+	private, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T, ed25519 is expected", key)
+	}
+
+	return private, e
+}
+
+// SignRequestWithKey returns the signature of v1 api request in the format verified by the service
+func SignRequestWithKey(key ed25519.PrivateKey, ip, path, hostname string) string {
+	return base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, RequestHMACMessage(ip, path, hostname)))
+}
+
+//
+//
+//
+
+// parsePublicKeys parses public keys by their hostnames; every hostname must be covered
+// by domains or grants of the entry, inactive grants are checked by authorization later
+func (m *YamlAuthorization) parsePublicKeys() (_ map[string][]ed25519.PublicKey, e error) {
+	parsed := make(map[string][]ed25519.PublicKey, len(m.PublicKeys))
+
+	hostnames := make([]string, 0, len(m.PublicKeys))
+	for hostname := range m.PublicKeys {
+		hostnames = append(hostnames, hostname)
+	}
+	sort.Strings(hostnames)
+
+	for _, hostname := range hostnames {
+		fqdn := normalizeFqdn(hostname)
+		if m.matchFqdn(fqdn, time.Time{}) == AUTHZ_HOSTNAME_MISMATCH {
+			return nil, fmt.Errorf("hostname %s is not covered by domains or grants of the entry", hostname)
+		}
+
+		if _, ok := parsed[fqdn]; ok {
+			return nil, fmt.Errorf("hostname %s is duplicated", hostname)
+		}
+
+		keys := make([]ed25519.PublicKey, 0, len(m.PublicKeys[hostname]))
+		for _, key := range m.PublicKeys[hostname] {
+			var raw []byte
+			if raw, e = base64.StdEncoding.DecodeString(key); e != nil {
+				return nil, fmt.Errorf("public key %s is not base64 encoded, %s", key, e.Error())
+			}
+
+			if len(raw) != ed25519.PublicKeySize {
+				return nil, fmt.Errorf("public key %s has unexpected size %d, raw ed25519 key is expected", key, len(raw))
+			}
+
+			keys = append(keys, ed25519.PublicKey(raw))
+		}
+
+		parsed[fqdn] = keys
+	}
+
+	return parsed, e
+}
@@ -0,0 +1,17 @@
+package auth
+
+import "github.com/MindHunter86/asmas/internal/metrics"
+
+var (
+	metricConfigFetches = metrics.NewCounter("asmas_auth_config_fetches_total",
+		"authorization config fetches by source and result (changed, unchanged, error)", "source", "result")
+	metricGrantExpiration = metrics.NewGauge("asmas_auth_grant_expiration_seconds",
+		"seconds left before time-bound grant expiration, negative for expired grants", "name", "domains")
+	metricConfigInfo = metrics.NewGauge("asmas_auth_config_info",
+		"provenance of the applied authorization config, the value is always 1",
+		"source", "blob", "commit", "author", "signer")
+	metricConfigSerial = metrics.NewGauge("asmas_auth_config_serial",
+		"highest serial of the applied authorization config")
+	metricConfigIssued = metrics.NewGauge("asmas_auth_config_issued_timestamp_seconds",
+		"issued_at of the applied authorization config, the oldest one for config directories")
+)
@@ -0,0 +1,176 @@
+package auth
+
+import (
+	"errors"
+	"path"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+)
+
+type patternKind uint8
+
+const (
+	PATTERN_EXACT patternKind = iota
+	PATTERN_GLOB
+	PATTERN_REGEXP
+)
+
+// domainPattern is a parsed item of YamlAuthorization.Domains;
+// all patterns are matched against lower-cased fqdn without the trailing dot
+type domainPattern struct {
+	raw  string
+	kind patternKind
+
+	labels []string
+	regexp *regexp.Regexp
+}
+
+// hostnames which must not be matched by any sane pattern
+var broadPatternProbes = []string{
+	"asmas-probe.invalid",
+	"asmas-probe.example.com.asmas-probe.invalid",
+}
+
+// parseDomainPatterns parses comma separated list of exact names and globs
+// or a single /regexp/; regexps are always anchored
+func parseDomainPatterns(domains string) (patterns []*domainPattern, e error) {
+	domains = strings.TrimSpace(domains)
+
+	if dlen := len(domains); dlen >= 2 && domains[0] == '/' && domains[dlen-1] == '/' {
+		var pattern *domainPattern
+		if pattern, e = newRegexpPattern(domains[1 : dlen-1]); e != nil {
+			return
+		}
+
+		return []*domainPattern{pattern}, e
+	}
+
+	for _, domain := range strings.Split(domains, ",") {
+		if domain = normalizeFqdn(domain); domain == "" {
+			return nil, errors.New("domains list contains an empty item")
+		}
+
+		var pattern *domainPattern
+		if pattern, e = newGlobPattern(domain); e != nil {
+			return
+		}
+
+		patterns = append(patterns, pattern)
+	}
+
+	return
+}
+
+func normalizeFqdn(fqdn string) string {
+	return strings.TrimSuffix(strings.ToLower(strings.TrimSpace(fqdn)), ".")
+}
+
+// match refuses hostnames with empty labels (i.e. a..example.com), so globs never match empty labels
+func (m *domainPattern) match(fqdn string) bool {
+	if hasEmptyLabel(fqdn) {
+		return false
+	}
+
+	switch m.kind {
+	case PATTERN_EXACT:
+		return m.raw == fqdn
+	case PATTERN_REGEXP:
+		return m.regexp.MatchString(fqdn)
+	}
+
+	labels := strings.Split(fqdn, ".")
+	if len(labels) != len(m.labels) {
+		return false
+	}
+
+	for i, label := range labels {
+		// pattern has been validated in newGlobPattern
+		if ok, _ := path.Match(m.labels[i], label); !ok {
+			return false
+		}
+	}
+
+	return true
+}
+
+// broadness returns a reason if the pattern looks accidentally broad
+func (m *domainPattern) broadness() string {
+	for _, probe := range broadPatternProbes {
+		if m.match(probe) {
+			return "pattern matches unrelated hostname " + probe
+		}
+	}
+
+	switch m.kind {
+	case PATTERN_GLOB:
+		// at least two literal labels are expected after the last wildcard label, i.e. *.example.com
+		var literals int
+		for i := len(m.labels) - 1; i >= 0 && !strings.ContainsAny(m.labels[i], "*?["); i-- {
+			literals++
+		}
+
+		if literals < 2 {
+			return "glob has a wildcard in the registrable domain"
+		}
+	case PATTERN_REGEXP:
+		if re, e := syntax.Parse(m.regexp.String(), syntax.Perl); e == nil && hasAnyCharOp(re) {
+			return "regexp contains an unescaped dot or any-char class, it matches any character including dots"
+		}
+	}
+
+	return ""
+}
+
+//
+//
+//
+
+func newGlobPattern(domain string) (_ *domainPattern, e error) {
+	if !strings.ContainsAny(domain, "*?[") {
+		return &domainPattern{raw: domain, kind: PATTERN_EXACT}, e
+	}
+
+	pattern := &domainPattern{raw: domain, kind: PATTERN_GLOB, labels: strings.Split(domain, ".")}
+	for _, label := range pattern.labels {
+		if label == "" {
+			return nil, errors.New("glob " + domain + " contains an empty label")
+		}
+
+		if _, e = path.Match(label, ""); e != nil {
+			return nil, errors.New("glob " + domain + " is invalid, " + e.Error())
+		}
+	}
+
+	return pattern, e
+}
+
+func newRegexpPattern(expr string) (_ *domainPattern, e error) {
+	pattern := &domainPattern{raw: "/" + expr + "/", kind: PATTERN_REGEXP}
+
+	// anchoring prevents /web.example.com/ from matching evilweb.example.com.attacker.net
+	if pattern.regexp, e = regexp.Compile(`(?i)^(?:` + expr + `)$`); e != nil {
+		return nil, e
+	}
+
+	return pattern, e
+}
+
+func hasEmptyLabel(fqdn string) bool {
+	return fqdn == "" || strings.HasPrefix(fqdn, ".") || strings.HasSuffix(fqdn, ".") || strings.Contains(fqdn, "..")
+}
+
+func hasAnyCharOp(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpAnyChar, syntax.OpAnyCharNotNL:
+		return true
+	}
+
+	for _, sub := range re.Sub {
+		if hasAnyCharOp(sub) {
+			return true
+		}
+	}
+
+	return false
+}
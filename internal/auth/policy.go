@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/ProtonMail/go-crypto/openpgp"
+	"gopkg.in/yaml.v3"
+)
+
+type (
+	// SignersPolicy restricts trusted signers to subsets of certificate names;
+	// signers of the keyring without policy entries are not allowed to sign configs
+	SignersPolicy struct {
+		Signers []*SignerPolicy
+
+		byfingerprint map[string]*SignerPolicy
+	}
+	SignerPolicy struct {
+		Fingerprint string
+		Names       []string
+
+		patterns []*domainPattern
+	}
+)
+
+// loadSignersPolicy returns nil if auth-signers-policy is not defined, all signers are unrestricted then
+func (m *AuthService) loadSignersPolicy() (_ *SignersPolicy, e error) {
+	if m.policyfile == "" {
+		return
+	}
+
+	var content []byte
+	if content, e = os.ReadFile(m.policyfile); e != nil {
+		return
+	}
+
+	policy := &SignersPolicy{}
+
+	decoder := yaml.NewDecoder(bytes.NewBuffer(content))
+	decoder.KnownFields(true)
+	if e = decoder.Decode(policy); e != nil {
+		return nil, errors.New("could not parse signers policy, " + e.Error())
+	}
+
+	policy.byfingerprint = make(map[string]*SignerPolicy, len(policy.Signers))
+	for i, signer := range policy.Signers {
+		if signer == nil || signer.Fingerprint == "" {
+			return nil, fmt.Errorf("signers policy item %d has no fingerprint", i)
+		}
+
+		signer.Fingerprint = normalizeFingerprint(signer.Fingerprint)
+		if _, ok := policy.byfingerprint[signer.Fingerprint]; ok {
+			return nil, errors.New("signers policy has duplicate fingerprint " + signer.Fingerprint)
+		}
+		policy.byfingerprint[signer.Fingerprint] = signer
+
+		if len(signer.Names) == 0 {
+			return nil, errors.New("signers policy item " + signer.Fingerprint + " has no names")
+		}
+
+		for _, names := range signer.Names {
+			var patterns []*domainPattern
+			if patterns, e = parseDomainPatterns(names); e != nil {
+				return nil, fmt.Errorf("signers policy item %s has invalid names %q, %s", signer.Fingerprint, names, e.Error())
+			}
+
+			signer.patterns = append(signer.patterns, patterns...)
+		}
+	}
+
+	return policy, e
+}
+
+// reportSignersPolicy logs restrictions of the keyring signers
+func (m *AuthService) reportSignersPolicy() {
+	if m.policy == nil {
+		m.log.Warn().Msg("signers policy is not defined, trusted signers may govern any certificate name")
+		return
+	}
+
+	known := make(map[string]struct{}, len(m.signers))
+	for _, entity := range m.signers {
+		fingerprint := signerFingerprint(entity)
+		known[fingerprint] = struct{}{}
+
+		if signer, ok := m.policy.byfingerprint[fingerprint]; ok {
+			m.log.Info().Msgf("trusted signer %s may govern certificate names %s", signerIdentity(entity), strings.Join(signer.Names, ", "))
+		} else {
+			m.log.Warn().Msgf("trusted signer %s has no signers policy item, its configs will be refused", signerIdentity(entity))
+		}
+	}
+
+	for _, signer := range m.policy.Signers {
+		if _, ok := known[signer.Fingerprint]; !ok {
+			m.log.Warn().Msgf("signers policy item %s is not found in the signers keyring", signer.Fingerprint)
+		}
+	}
+}
+
+// checkSignerPolicy refuses signers without policy items, their signatures are not counted
+func (m *AuthService) checkSignerPolicy(signer *openpgp.Entity) error {
+	if m.policy == nil {
+		return nil
+	}
+
+	if _, ok := m.policy.byfingerprint[signerFingerprint(signer)]; !ok {
+		return errors.New("signer " + signerIdentity(signer) + " is not allowed by signers policy")
+	}
+
+	return nil
+}
+
+// isGovernedBy reports whether auth-signers-threshold of counted signers of the entry may govern
+// its certificate name, signers of other names are not counted; entries of unsigned configs
+// validated by cli are not checked
+func (m *AuthService) isGovernedBy(entity *YamlAuthorization) bool {
+	if m.policy == nil || len(entity.signers) == 0 {
+		return true
+	}
+
+	var governing int
+	name := normalizeFqdn(entity.Name)
+	for _, fingerprint := range entity.signers {
+		signer, ok := m.policy.byfingerprint[fingerprint]
+		if !ok {
+			continue
+		}
+
+		for _, pattern := range signer.patterns {
+			if pattern.match(name) {
+				governing++
+				break
+			}
+		}
+	}
+
+	return governing != 0 && governing >= m.threshold
+}
+
+//
+//
+//
+
+func normalizeFingerprint(fingerprint string) string {
+	return strings.ToUpper(strings.ReplaceAll(fingerprint, " ", ""))
+}
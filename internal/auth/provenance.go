@@ -0,0 +1,106 @@
+package auth
+
+import (
+	"time"
+)
+
+type (
+	// ConfigCommit is the last commit touching the config path,
+	// it's known for github and git sources only
+	ConfigCommit struct {
+		Sha    string
+		Author string
+		Time   time.Time
+	}
+
+	// ConfigProvenance describes the origin of the applied authorization config
+	ConfigProvenance struct {
+		Source            string            `json:"source"`
+		Name              string            `json:"name"`
+		BlobSha           string            `json:"blob_sha"`
+		CommitSha         string            `json:"commit_sha,omitempty"`
+		CommitAuthor      string            `json:"commit_author,omitempty"`
+		CommitTime        *time.Time        `json:"commit_time,omitempty"`
+		Signer            string            `json:"signer"`
+		SignerFingerprint string            `json:"signer_fingerprint"`
+		Serial            uint64            `json:"serial"`
+		IssuedAt          *time.Time        `json:"issued_at,omitempty"`
+		Files             []*ConfigFileInfo `json:"files,omitempty"`
+		AppliedAt         time.Time         `json:"applied_at"`
+		Restored          bool              `json:"restored"`
+	}
+	ConfigFileInfo struct {
+		Name              string `json:"name"`
+		BlobSha           string `json:"blob_sha"`
+		SignerFingerprint string `json:"signer_fingerprint"`
+	}
+)
+
+// ConfigProvenance returns the origin of the applied authorization config or nil
+func (m *AuthService) ConfigProvenance() (provenance *ConfigProvenance) {
+	actionWithRLock(&m.mu, func() {
+		if m.provenance != nil {
+			copied := *m.provenance
+			provenance = &copied
+		}
+	})
+
+	return
+}
+
+//
+//
+//
+
+func newConfigProvenance(source string, payload *ConfigPayload, restored bool) *ConfigProvenance {
+	provenance := &ConfigProvenance{
+		Source:            source,
+		Name:              payload.Name,
+		BlobSha:           payload.Sha,
+		Signer:            payload.Signer,
+		SignerFingerprint: payload.Fingerprint,
+		AppliedAt:         time.Now(),
+		Restored:          restored,
+	}
+
+	var issuedat time.Time
+	if provenance.Serial, issuedat = configIssuance(payload); !issuedat.IsZero() {
+		provenance.IssuedAt = &issuedat
+	}
+
+	if payload.Commit != nil {
+		commitTime := payload.Commit.Time
+		provenance.CommitSha, provenance.CommitAuthor, provenance.CommitTime =
+			payload.Commit.Sha, payload.Commit.Author, &commitTime
+	}
+
+	for _, part := range payload.Parts {
+		provenance.Files = append(provenance.Files, &ConfigFileInfo{
+			Name:              part.Name,
+			BlobSha:           part.Sha,
+			SignerFingerprint: part.Fingerprint,
+		})
+	}
+
+	return provenance
+}
+
+// reportConfigProvenance must be called under the write lock
+func (m *AuthService) reportConfigProvenance(provenance *ConfigProvenance) {
+	m.provenance = provenance
+
+	// the info metric has the only sample of the applied config
+	metricConfigInfo.Reset()
+	metricConfigInfo.Set(1, provenance.Source, provenance.BlobSha, provenance.CommitSha,
+		provenance.CommitAuthor, provenance.SignerFingerprint)
+
+	if provenance.CommitSha == "" {
+		m.log.Info().Msgf("authorization config %s provenance - blob %s, signer %s; commit is unknown",
+			provenance.Name, provenance.BlobSha, provenance.Signer)
+		return
+	}
+
+	m.log.Info().Msgf("authorization config %s provenance - blob %s, commit %s by %s at %s, signer %s",
+		provenance.Name, provenance.BlobSha, provenance.CommitSha, provenance.CommitAuthor,
+		provenance.CommitTime.Format(time.RFC3339), provenance.Signer)
+}
@@ -0,0 +1,205 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// highest accepted serials of config files are persisted in the state directory,
+// older validly signed configs are refused as replayed ones
+const stateSerialsName = "serials.json"
+
+// issued_at in the future is allowed within this clock skew
+const configIssuedAtSkew = 5 * time.Minute
+
+type configSerial struct {
+	Serial uint64 `json:"serial"`
+	Digest string `json:"digest"`
+}
+
+// checkConfigSerials refuses configs with serials lower than accepted ones and configs
+// changed without serial increment; files of the config directory are checked independently
+// and must carry their own names in the signed content, so they can't be replayed under new names
+func (m *AuthService) checkConfigSerials(payload *ConfigPayload) (e error) {
+	for key, part := range serialParts(payload) {
+		if part.issuedat.After(time.Now().Add(configIssuedAtSkew)) {
+			return fmt.Errorf("%s: config is issued in the future at %s", key, part.issuedat.Format(time.RFC3339))
+		}
+	}
+
+	if !m.serialsrequired {
+		return
+	}
+
+	for key, part := range serialParts(payload) {
+		if part.serial == 0 {
+			return fmt.Errorf("%s: config has no serial, it's required by auth-config-serials", key)
+		}
+
+		if len(payload.Parts) != 0 && part.file != key {
+			return fmt.Errorf("%s: config is signed for file %q, it may be replayed under another name", key, part.file)
+		}
+
+		accepted, ok := m.serials[key]
+		if !ok {
+			continue
+		}
+
+		switch {
+		case part.serial < accepted.Serial:
+			return fmt.Errorf("%s: config serial %d is lower than accepted serial %d, the config may be replayed",
+				key, part.serial, accepted.Serial)
+		case part.serial == accepted.Serial && part.digest != accepted.Digest:
+			return fmt.Errorf("%s: config has been changed without serial increment, serial %d is already accepted",
+				key, part.serial)
+		}
+	}
+
+	return
+}
+
+// acceptConfigSerials saves serials of the applied config, the state directory is optional
+func (m *AuthService) acceptConfigSerials(payload *ConfigPayload) (e error) {
+	if !m.serialsrequired {
+		return
+	}
+
+	for key, part := range serialParts(payload) {
+		if accepted, ok := m.serials[key]; ok && part.serial < accepted.Serial {
+			continue
+		}
+
+		m.serials[key] = &configSerial{Serial: part.serial, Digest: part.digest}
+	}
+
+	if m.statedir == "" {
+		return
+	}
+
+	var content []byte
+	if content, e = json.Marshal(m.serials); e != nil {
+		return
+	}
+
+	return writeStateFile(m.statedir, stateSerialsName, content)
+}
+
+func (m *AuthService) loadConfigSerials() (e error) {
+	m.serials = make(map[string]*configSerial)
+	if !m.serialsrequired {
+		return
+	}
+
+	if m.statedir == "" {
+		m.log.Warn().Msg("state directory is not defined, accepted config serials are kept in memory only")
+		return
+	}
+
+	var content []byte
+	if content, e = os.ReadFile(filepath.Join(m.statedir, stateSerialsName)); errors.Is(e, os.ErrNotExist) {
+		return nil
+	} else if e != nil {
+		return
+	}
+
+	if e = json.Unmarshal(content, &m.serials); e != nil {
+		return errors.New("could not parse accepted config serials, " + e.Error())
+	}
+
+	for key, serial := range m.serials {
+		m.log.Info().Msgf("loaded accepted config serial %d of %s", serial.Serial, key)
+	}
+
+	return
+}
+
+// isConfigTooOld reports configs issued earlier than auth-config-max-age;
+// configs without issued_at are too old if the limit is set
+func (m *AuthService) isConfigTooOld() bool {
+	return m.configmaxage != 0 && m.authlist != nil &&
+		(m.issuedat.IsZero() || time.Since(m.issuedat) > m.configmaxage)
+}
+
+// checkConfigAge alerts about the applied config which is not re-issued in time
+func (m *AuthService) checkConfigAge() {
+	var serial uint64
+	var issuedat time.Time
+	var tooold bool
+	actionWithRLock(&m.mu, func() {
+		serial, issuedat, tooold = m.serial, m.issuedat, m.isConfigTooOld()
+	})
+
+	metricConfigSerial.Set(float64(serial))
+	if !issuedat.IsZero() {
+		metricConfigIssued.Set(float64(issuedat.Unix()))
+	}
+
+	if !tooold {
+		return
+	}
+
+	action := "private key requests are still served"
+	if m.configagedeny {
+		action = "private key requests are denied"
+	}
+
+	if issuedat.IsZero() {
+		m.log.Error().Msg("applied authorization config has no issued_at, it's required by auth-config-max-age; " + action)
+		return
+	}
+
+	m.log.Error().Msgf("applied authorization config is issued %s ago at %s, it's older than auth-config-max-age; %s",
+		time.Since(issuedat).Round(time.Second).String(), issuedat.Format(time.RFC3339), action)
+}
+
+//
+//
+//
+
+// serialParts returns signed files of the payload by their state names
+func serialParts(payload *ConfigPayload) map[string]*ConfigPayload {
+	if len(payload.Parts) == 0 {
+		return map[string]*ConfigPayload{stateConfigName: payload}
+	}
+
+	parts := make(map[string]*ConfigPayload, len(payload.Parts))
+	for _, part := range payload.Parts {
+		parts[filepath.Base(part.Name)] = part
+	}
+
+	return parts
+}
+
+// configIssuance returns the highest serial and the oldest issued_at of the payload files;
+// every file of the config directory must be re-issued in time
+func configIssuance(payload *ConfigPayload) (serial uint64, issuedat time.Time) {
+	var missing bool
+	for _, part := range serialParts(payload) {
+		if part.serial > serial {
+			serial = part.serial
+		}
+
+		if part.issuedat.IsZero() {
+			missing = true
+		} else if issuedat.IsZero() || part.issuedat.Before(issuedat) {
+			issuedat = part.issuedat
+		}
+	}
+
+	if missing {
+		return serial, time.Time{}
+	}
+
+	return
+}
+
+func configDigest(validated []byte) string {
+	hash := sha256.Sum256(validated)
+	return hex.EncodeToString(hash[:])
+}
@@ -4,7 +4,8 @@ import (
 	"context"
 	"crypto"
 	"errors"
-	"regexp"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
 
@@ -19,17 +20,51 @@ import (
 type AuthService struct {
 	token string
 
-	client       *gclient.HttpClient
+	source       ConfigSource
 	pullinterval time.Duration
 	pullerrdelay time.Duration
 
 	debugskipgithub bool
 
-	signers   openpgp.EntityList
-	pgpconfig *packet.Config
-
-	mu       sync.RWMutex
-	authlist *YamlConfig
+	keyring    string
+	signers    openpgp.EntityList
+	policyfile string
+	policy     *SignersPolicy
+	threshold  int
+	pgpconfig  *packet.Config
+
+	// strictyaml rejects unknown config keys, it's used by cli validation
+	strictyaml bool
+
+	trigger    chan struct{}
+	hookdelay  time.Duration
+	hooksecret string
+	hookrepo   string
+	hookbranch string
+	hookpath   string
+
+	statedir    string
+	statemaxage time.Duration
+
+	grantwarning time.Duration
+
+	diffhistory int
+	diffs       []*ConfigDiff
+
+	mu          sync.RWMutex
+	authlist    *YamlConfig
+	appliedsha  string
+	appliedtime time.Time
+	degraded    bool
+	provenance  *ConfigProvenance
+	serial      uint64
+	issuedat    time.Time
+
+	// highest accepted serials by config file, they are used by the update loop only
+	serials         map[string]*configSerial
+	serialsrequired bool
+	configmaxage    time.Duration
+	configagedeny   bool
 
 	log   *zerolog.Logger
 	done  func() <-chan struct{}
@@ -37,26 +72,62 @@ type AuthService struct {
 }
 
 func NewAuthService(c context.Context, cc *cli.Context) *AuthService {
-	return &AuthService{
+	service := &AuthService{
 		token: cc.String("auth-sign-token"),
 
-		client:       gclient.NewHttpClient(cc, c.Value(utils.CKeyLogger).(*zerolog.Logger)),
 		pullinterval: cc.Duration("auth-github-pull-interval"),
 		pullerrdelay: cc.Duration("auth-github-pull-error-delay"),
 
+		keyring:    cc.String("auth-signers-keyring"),
+		policyfile: cc.String("auth-signers-policy"),
+		threshold:  cc.Int("auth-signers-threshold"),
 		pgpconfig: &packet.Config{
 			DefaultHash: crypto.SHA512,
 		},
 
 		debugskipgithub: cc.Bool("debug-skip-github-connect"),
 
+		trigger:    make(chan struct{}, 1),
+		hookdelay:  cc.Duration("auth-webhook-coalesce-delay"),
+		hooksecret: cc.String("auth-webhook-secret"),
+		hookrepo:   cc.String("auth-github-repo"),
+		hookbranch: cc.String("auth-github-branch"),
+		hookpath:   cc.String("auth-github-path"),
+
+		statedir:    cc.String("auth-state-dir"),
+		statemaxage: cc.Duration("auth-state-max-age"),
+
+		grantwarning: cc.Duration("auth-grant-expiry-warning"),
+
+		diffhistory: cc.Int("auth-diff-history"),
+
+		serialsrequired: cc.Bool("auth-config-serials"),
+		configmaxage:    cc.Duration("auth-config-max-age"),
+		configagedeny:   cc.Bool("auth-config-max-age-deny"),
+
 		log:   c.Value(utils.CKeyLogger).(*zerolog.Logger),
 		done:  c.Done,
 		abort: c.Value(utils.CKeyAbortFunc).(context.CancelFunc),
 	}
+
+	source, e := NewConfigSource(cc, service.log)
+	if e != nil {
+		service.log.Error().Msg("an error occurred while initializing config source - " + e.Error())
+	} else {
+		service.source = source
+	}
+
+	return service
 }
 
 func (m *AuthService) Boostrap() {
+	if m.source == nil {
+		m.log.Error().Msg("config source is not initialized, check auth-source flags")
+		m.abort()
+		return
+	}
+	m.log.Info().Msgf("authorization config will be loaded from %s source", m.source.String())
+
 	var e error
 	if m.signers, e = m.loadConfigSigners(); e != nil {
 		m.log.Error().Msg("an error occurred while loading signers - " + e.Error())
@@ -70,30 +141,32 @@ func (m *AuthService) Boostrap() {
 		}
 	}
 
-	if m.authlist, e = m.loadAuthorizationList(); e != nil {
-		m.log.Error().Msg("an error occurred while loading authlist - " + e.Error())
+	if m.policy, e = m.loadSignersPolicy(); e != nil {
+		m.log.Error().Msg("an error occurred while loading signers policy - " + e.Error())
 		m.abort()
 		return
 	}
+	m.reportSignersPolicy()
 
-	m.loop()
-}
-
-func (m *AuthService) AuthorizeHostname(name, hostname string) (ok bool, _ error) {
-	if !m.isApiReady() {
-		return false, errors.New("auth service api is not ready yet")
+	if e = m.loadConfigSerials(); e != nil {
+		m.log.Error().Msg("an error occurred while loading accepted config serials - " + e.Error())
+		m.abort()
+		return
 	}
 
-	ok = actionReturbableWithRLock[bool](&m.mu, func() bool {
-		var auth *YamlAuthorization
-		if auth = m.authlist.authorizationByFqdn(name); auth == nil {
-			return false
-		}
+	if _, e = m.updateAuthorizationList(); e != nil {
+		m.log.Error().Msg("an error occurred while loading authlist - " + e.Error())
 
-		return auth.isAuthorizedFqdn(hostname)
-	})
+		if e = m.restoreAuthorizationList(); e != nil {
+			m.log.Error().Msg("an error occurred while loading last-known-good authlist - " + e.Error())
+			m.abort()
+			return
+		}
+	}
 
-	return
+	m.checkExpiringGrants()
+	m.checkConfigAge()
+	m.loop()
 }
 
 //
@@ -105,6 +178,12 @@ func (m *AuthService) loop() {
 	defer m.log.Debug().Msg("auth service update loop has been closed")
 
 	update := time.NewTimer(m.pullinterval)
+	if actionReturbableWithRLock[bool](&m.mu, func() bool { return m.degraded }) {
+		update.Reset(m.pullerrdelay)
+	}
+
+	// triggered is set while the update scheduled by a trigger is pending
+	var triggered bool
 
 LOOP:
 	for {
@@ -112,24 +191,61 @@ LOOP:
 		case <-m.done():
 			m.log.Info().Msg("internal abort() has been caught; initiate application closing...")
 			break LOOP
+		case <-m.trigger:
+			// all triggers received in the delay window are coalesced into the pending update,
+			// they never postpone it, so steady pushes can't delay the update forever
+			if triggered {
+				m.log.Debug().Msg("authorization list update has been triggered, it's already pending")
+				continue
+			}
+			triggered = true
+
+			m.log.Info().Msgf("authorization list update has been triggered, it will be started in %s", m.hookdelay.String())
+			if !update.Stop() {
+				select {
+				case <-update.C:
+				default:
+				}
+			}
+			update.Reset(m.hookdelay)
 		case <-update.C:
 			update.Stop()
+			triggered = false
 
 			var e error
+			var changed bool
 			started := time.Now()
 
-			if e = m.updateAuthorizationList(); e != nil {
+			changed, e = m.updateAuthorizationList()
+			m.checkExpiringGrants()
+			m.checkConfigAge()
+
+			if e != nil {
 				m.log.Error().Msg("an error occurred in auth update loop, " + e.Error())
-				update.Reset(m.pullerrdelay)
+				update.Reset(m.errorDelay(e))
 				continue
 			}
 
-			m.log.Info().Msg("authorization list has been updated for " + time.Since(started).String())
+			if changed {
+				m.log.Info().Msg("authorization list has been updated for " + time.Since(started).String())
+			} else {
+				m.log.Info().Msg("authorization list is up to date, checked for " + time.Since(started).String())
+			}
 			update.Reset(m.pullinterval)
 		}
 	}
 }
 
+// errorDelay honours the delay requested by the source, i.e. github rate limits
+func (m *AuthService) errorDelay(e error) time.Duration {
+	if delay := gclient.RetryAfter(e); delay > m.pullerrdelay {
+		m.log.Warn().Msg("next authorization list update is delayed by source for " + delay.Round(time.Second).String())
+		return delay
+	}
+
+	return m.pullerrdelay
+}
+
 func (m *AuthService) isApiReady() (ok bool) {
 	ok = actionReturbableWithRLock[bool](&m.mu, func() bool {
 		return m.authlist != nil
@@ -138,80 +254,297 @@ func (m *AuthService) isApiReady() (ok bool) {
 	return
 }
 
-func (m *AuthService) updateAuthorizationList() (e error) {
+func (m *AuthService) updateAuthorizationList() (changed bool, e error) {
+	if m.debugskipgithub {
+		return
+	}
+
+	var payload *ConfigPayload
+	if payload, e = m.source.FetchConfig(); errors.Is(e, ErrConfigNotModified) {
+		m.confirmAuthorizationList("etag")
+		return false, nil
+	} else if e != nil {
+		metricConfigFetches.Inc(m.source.String(), "error")
+		return
+	}
+
+	// skip verification and parsing of the already applied config
+	if actionReturbableWithRLock[bool](&m.mu, func() bool {
+		return m.authlist != nil && payload.Sha != "" && payload.Sha == m.appliedsha
+	}) {
+		m.commitSourcePayload(payload)
+		m.confirmAuthorizationList("sha")
+		return false, nil
+	}
+
 	var newlist *YamlConfig
-	if newlist, e = m.loadAuthorizationList(); e != nil {
+	if newlist, e = m.loadAuthorizationList(payload); e != nil {
+		metricConfigFetches.Inc(m.source.String(), "error")
+		return
+	}
+
+	if e = m.checkConfigSerials(payload); e != nil {
+		metricConfigFetches.Inc(m.source.String(), "rollback")
 		return
 	}
 
+	if err := m.acceptConfigSerials(payload); err != nil {
+		m.log.Warn().Msg("could not save accepted config serials in state directory, " + err.Error())
+	}
+
+	// the state directory is optional, its errors must not fail the update
+	if err := m.saveConfigState(payload); err != nil {
+		m.log.Warn().Msg("could not save last-known-good config in state directory, " + err.Error())
+	}
+
 	actionWithLock(&m.mu, func() {
-		m.authlist = newlist
+		m.reportConfigDiff(m.authlist, newlist, payload)
+		m.reportConfigProvenance(newConfigProvenance(m.source.String(), payload, false))
+		m.authlist, m.appliedsha, m.appliedtime = newlist, payload.Sha, time.Now()
+		m.serial, m.issuedat = configIssuance(payload)
+
+		if m.degraded {
+			m.log.Info().Msg("authorization config has been received from source, leaving degraded mode")
+		}
+		m.degraded = false
 	})
-	return
+
+	m.commitSourcePayload(payload)
+	metricConfigFetches.Inc(m.source.String(), "changed")
+	m.log.Info().Msgf("authorization config %s with hash %s has been applied", payload.Name, payload.Sha)
+	return true, e
 }
 
-func (m *AuthService) loadAuthorizationList() (_ *YamlConfig, e error) {
-	if m.debugskipgithub {
+// commitSourcePayload lets the source skip unchanged configs on next fetches,
+// it must be called for applied payloads only
+func (m *AuthService) commitSourcePayload(payload *ConfigPayload) {
+	if source, ok := m.source.(committableSource); ok {
+		source.Commit(payload)
+	}
+}
+
+// confirmAuthorizationList marks the applied config as up to date with the source
+func (m *AuthService) confirmAuthorizationList(reason string) {
+	if e := m.touchConfigState(); e != nil {
+		m.log.Warn().Msg("could not update last-known-good config in state directory, " + e.Error())
+	}
+
+	actionWithLock(&m.mu, func() {
+		m.appliedtime = time.Now()
+
+		if m.degraded {
+			m.log.Info().Msg("authorization config has been confirmed by source, leaving degraded mode")
+		}
+		m.degraded = false
+	})
+
+	metricConfigFetches.Inc(m.source.String(), "unchanged")
+	m.log.Debug().Msgf("authorization config has not been changed (detected by %s)", reason)
+}
+
+// restoreAuthorizationList applies the last-known-good config from the state directory;
+// the service is working in degraded mode until the next successful update
+func (m *AuthService) restoreAuthorizationList() (e error) {
+	var payload *ConfigPayload
+	var saved time.Time
+	if payload, saved, e = m.loadConfigState(); e != nil {
 		return
 	}
 
-	var response *gclient.GithubResponse
-	if response, e = m.client.FetchConfigFromGithub(); e != nil {
+	var newlist *YamlConfig
+	if newlist, e = m.loadAuthorizationList(payload); e != nil {
 		return
 	}
 
-	if e = m.client.ValidateGithubResponse(response); e != nil {
+	// the state directory may be replaced with an older config as well
+	if e = m.checkConfigSerials(payload); e != nil {
+		return
+	}
+
+	actionWithLock(&m.mu, func() {
+		m.reportConfigProvenance(newConfigProvenance(m.source.String(), payload, true))
+		m.authlist, m.appliedsha, m.appliedtime, m.degraded = newlist, payload.Sha, saved, true
+		m.serial, m.issuedat = configIssuance(payload)
+	})
+
+	m.log.Warn().Msgf("authorization list has been restored from state directory, config age %s; working in degraded mode",
+		time.Since(saved).Round(time.Second).String())
+	return
+}
+
+func (m *AuthService) loadAuthorizationList(payload *ConfigPayload) (_ *YamlConfig, e error) {
+	var authlist *YamlConfig
+	if len(payload.Parts) == 0 {
+		if authlist, e = m.loadConfigPart(payload); e != nil {
+			return
+		}
+	} else if authlist, e = m.mergeConfigParts(payload); e != nil {
 		return
 	}
 
+	var errs []error
+	actionWithRLock(&m.mu, func() {
+		errs = m.validateAuthorizationList(authlist)
+	})
+
+	if len(errs) != 0 {
+		for _, err := range errs {
+			m.log.Error().Msg("config validation error - " + err.Error())
+		}
+
+		return nil, errors.New("could not validate received config with authorized domains, check logs")
+	}
+
+	return authlist, e
+}
+
+func (m *AuthService) loadConfigPart(payload *ConfigPayload) (_ *YamlConfig, e error) {
 	var validated []byte
-	if validated, e = m.validateConfigSign(response.Content); e != nil {
+	var signers openpgp.EntityList
+	if validated, signers, e = m.validateConfigSign(payload.Content); e != nil {
 		return
 	}
 
+	fingerprints := signersFingerprints(signers)
+	payload.Signer, payload.Fingerprint = signersIdentity(signers), strings.Join(fingerprints, ",")
+
 	var authlist *YamlConfig
 	if authlist, e = m.unmarshalYamlConfig(validated); e != nil {
 		return
 	}
 
-	if ok := actionReturbableWithRLock[bool](&m.mu, func() bool {
-		return m.validateAuthorizationList(authlist)
-	}); !ok {
-		return nil, errors.New("could not validate received config with authorized domains, check logs")
+	payload.serial, payload.issuedat, payload.file = authlist.Serial, authlist.IssuedAt, authlist.File
+	payload.digest = configDigest(validated)
+	authlist.setSigners(fingerprints)
+
+	return authlist, e
+}
+
+// mergeConfigParts verifies each file of the config directory with its own signature and
+// merges authorization lists; duplicate names are detected by validateAuthorizationList
+func (m *AuthService) mergeConfigParts(payload *ConfigPayload) (_ *YamlConfig, e error) {
+	authlist := &YamlConfig{}
+	signers, fingerprints := make([]string, 0, len(payload.Parts)), make([]string, 0, len(payload.Parts))
+	seen := make(map[string]struct{}, len(payload.Parts))
+
+	for _, part := range payload.Parts {
+		var partlist *YamlConfig
+		if partlist, e = m.loadConfigPart(part); e != nil {
+			return nil, fmt.Errorf("%s: %s", part.Name, e.Error())
+		}
+
+		for _, entity := range partlist.AuthorizationList {
+			if entity != nil {
+				entity.file = part.Name
+			}
+		}
+
+		authlist.AuthorizationList = append(authlist.AuthorizationList, partlist.AuthorizationList...)
+
+		if _, ok := seen[part.Signer]; !ok {
+			seen[part.Signer] = struct{}{}
+			signers, fingerprints = append(signers, part.Signer), append(fingerprints, part.Fingerprint)
+		}
 	}
 
+	payload.Signer, payload.Fingerprint = strings.Join(signers, ", "), strings.Join(fingerprints, ",")
 	return authlist, e
 }
 
-func (m *AuthService) validateAuthorizationList(authlist *YamlConfig) (ok bool) {
-	var entityname string
+func (m *AuthService) validateAuthorizationList(authlist *YamlConfig) (errs []error) {
+	names := make(map[string]*YamlAuthorization, len(authlist.AuthorizationList))
 
+	// one broken entry must never crash the update loop or the cli
+	var entityname string
 	defer func() {
 		if r := recover(); r != nil {
-			m.log.Error().Msgf("panic has been cauth on processing %s regexp, regexp is invalid", entityname)
-			m.log.Debug().Msg("panic has been caught, seems regexp compilation was failed")
 			m.log.Trace().Msgf("%+v", r)
+			errs = append(errs, fmt.Errorf("BUG! panic has been caught while validating entry %s, %v", entityname, r))
 		}
 	}()
 
-	for _, entity := range authlist.AuthorizationList {
-		// save entityname for panic errors
+	for i, entity := range authlist.AuthorizationList {
+		if entity == nil {
+			errs = append(errs, fmt.Errorf("authorization list item %d is empty", i))
+			continue
+		}
 		entityname = entity.Name
 
-		if entity.Domains == "" {
+		if entity.Name == "" {
+			errs = append(errs, entity.errorf("name is empty"))
+			continue
+		}
+
+		if !m.isGovernedBy(entity) {
+			errs = append(errs, entity.errorf("signers %s: less than %d of them may govern this certificate name by signers policy",
+				strings.Join(entity.signers, ", "), m.threshold))
+			continue
+		}
+
+		if duplicate, ok := names[entity.Name]; ok {
+			errs = append(errs, entity.errorf("duplicate name, first defined on %s", duplicate.location()))
+			continue
+		}
+		names[entity.Name] = entity
+
+		// entries with time-bound grants only have no permanent domains
+		if entity.Domains == "" && len(entity.Grants) == 0 {
 			entity.Domains = entity.Name
+		}
+
+		var e error
+		if entity.Domains != "" {
+			if entity.patterns, e = parseDomainPatterns(entity.Domains); e != nil {
+				errs = append(errs, entity.errorf("invalid domains, %s", e.Error()))
+				continue
+			}
+		}
+
+		if entity.networks, e = parseAllowedNetworks(entity.AllowedNetworks); e != nil {
+			errs = append(errs, entity.errorf("invalid allowed_networks, %s", e.Error()))
 			continue
 		}
 
-		// check for regexp template
-		domlen := len(entity.Domains)
-		if entity.Domains[:1] == "/" && entity.Domains[domlen-1:domlen] == "/" {
-			entity.domregexp = regexp.MustCompile(entity.Domains[1 : domlen-1])
+		var invalid bool
+		for i, grant := range entity.Grants {
+			if grant == nil {
+				errs, invalid = append(errs, entity.errorf("grant item %d is empty", i)), true
+				break
+			}
+
+			if e = grant.parse(); e != nil {
+				errs, invalid = append(errs, entity.errorf("invalid grant %s, %s", grant.Domains, e.Error())), true
+				break
+			}
+
+			if !grant.NotAfter.IsZero() && time.Now().After(grant.NotAfter) {
+				m.log.Warn().Msg("config validation warning - " +
+					entity.errorf("grant %s has been expired at %s, it may be removed", grant.Domains, grant.NotAfter.Format(time.RFC3339)).Error())
+			}
+		}
+
+		if invalid {
+			continue
+		}
+
+		if entity.scopes, e = parseScopes(entity.Scopes); e != nil {
+			errs = append(errs, entity.errorf("invalid scopes, %s", e.Error()))
+			continue
+		}
+
+		if entity.keys, e = entity.parsePublicKeys(); e != nil {
+			errs = append(errs, entity.errorf("invalid public_keys, %s", e.Error()))
+			continue
+		}
+
+		for _, pattern := range entity.patterns {
+			if reason := pattern.broadness(); reason != "" {
+				m.log.Warn().Msg("config validation warning - " + entity.errorf("%s looks too broad, %s", pattern.raw, reason).Error())
+			}
 		}
 
 		m.log.Info().Msgf("loaded authorized domain with id %s", entity.Name)
 	}
 
-	ok = true
 	return
 }
@@ -6,61 +6,178 @@ import (
 	"crypto/sha256"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
 
 	"github.com/MindHunter86/asmas/internal/utils"
 	"github.com/ProtonMail/go-crypto/openpgp"
+	"github.com/ProtonMail/go-crypto/openpgp/armor"
 	"github.com/ProtonMail/go-crypto/openpgp/clearsign"
+	pgperrors "github.com/ProtonMail/go-crypto/openpgp/errors"
 	futils "github.com/gofiber/fiber/v2/utils"
 )
 
-func (*AuthService) loadConfigSigners() (openpgp.EntityList, error) {
-	return openpgp.ReadArmoredKeyRing(bytes.NewBuffer(futils.UnsafeBytes(utils.SIGNER_PGP_PUBLIC_KEY)))
+func (m *AuthService) loadConfigSigners() (signers openpgp.EntityList, e error) {
+	if m.keyring == "" {
+		signers, e = openpgp.ReadArmoredKeyRing(bytes.NewBuffer(futils.UnsafeBytes(utils.SIGNER_PGP_PUBLIC_KEY)))
+	} else {
+		signers, e = readArmoredKeyRingFile(m.keyring)
+	}
+
+	if e != nil {
+		return
+	}
+
+	return signers, m.checkSignersThreshold(signers)
+}
+
+// checkSignersThreshold refuses thresholds which can never be reached by distinct keyring signers
+func (m *AuthService) checkSignersThreshold(signers openpgp.EntityList) error {
+	distinct := make(map[string]struct{}, len(signers))
+	for _, entity := range signers {
+		distinct[signerFingerprint(entity)] = struct{}{}
+	}
+
+	if m.threshold < 1 || m.threshold > len(distinct) {
+		return fmt.Errorf("auth-signers-threshold %d must be between 1 and %d distinct signers of the keyring",
+			m.threshold, len(distinct))
+	}
+
+	return nil
+}
+
+func readArmoredKeyRingFile(path string) (_ openpgp.EntityList, e error) {
+	var fd *os.File
+	if fd, e = os.Open(path); e != nil {
+		return
+	}
+	defer fd.Close()
+
+	return openpgp.ReadArmoredKeyRing(fd)
 }
 
-func (m *AuthService) validateConfigSign(payload []byte) (_ []byte, e error) {
+// validateConfigSign verifies all signatures of the clearsigned payload and returns distinct trusted
+// signers allowed by signers policy; the payload is refused if there are less than auth-signers-threshold of them
+func (m *AuthService) validateConfigSign(payload []byte) (_ []byte, signers openpgp.EntityList, e error) {
 	var signblock *clearsign.Block
 	if signblock, _ = clearsign.Decode(payload); signblock == nil {
-		return nil, errors.New("could not decode PGP signed file, clear sign not found")
+		return nil, nil, errors.New("could not decode PGP signed file, clear sign not found")
 	}
 
-	var signer *openpgp.Entity
-	if signer, e = signblock.VerifySignature(m.signers, m.pgpconfig); e != nil {
+	// the signature block may contain signatures of several keys, each trusted key is checked separately
+	var signature []byte
+	if signature, e = io.ReadAll(signblock.ArmoredSignature.Body); e != nil {
 		return
 	}
 
-	for _, identity := range signer.Identities {
-		m.log.Info().Msgf("found trusted identity %s in received payload", identity.Name)
+	seen := make(map[string]struct{}, len(m.signers))
+	for _, entity := range m.signers {
+		fingerprint := signerFingerprint(entity)
+		if _, ok := seen[fingerprint]; ok {
+			continue
+		}
+		seen[fingerprint] = struct{}{}
+
+		block := *signblock
+		block.ArmoredSignature = &armor.Block{Type: signblock.ArmoredSignature.Type, Body: bytes.NewReader(signature)}
+
+		var signer *openpgp.Entity
+		if signer, e = block.VerifySignature(openpgp.EntityList{entity}, m.pgpconfig); errors.Is(e, pgperrors.ErrUnknownIssuer) {
+			continue
+		} else if e != nil {
+			m.log.Warn().Msgf("signature of %s is not counted, %s", signerIdentity(entity), e.Error())
+			continue
+		}
+
+		if e = m.checkSignerPolicy(signer); e != nil {
+			m.log.Warn().Msg("signature is not counted, " + e.Error())
+			continue
+		}
+
+		signers = append(signers, signer)
+		m.log.Info().Msgf("signature of trusted signer %s is counted (%d of %d required)",
+			signerIdentity(signer), len(signers), m.threshold)
+	}
+
+	if len(signers) == 0 {
+		return nil, nil, errors.New("there are no valid signatures of trusted signers allowed by signers policy")
+	} else if len(signers) < m.threshold {
+		return nil, nil, fmt.Errorf("payload has valid signatures of %d distinct trusted signers, %d are required by auth-signers-threshold",
+			len(signers), m.threshold)
 	}
 
 	m.log.Info().Msg("received payload has been verified and approved")
-	return signblock.Bytes, e
+	return signblock.Bytes, signers, nil
 }
 
-func (*AuthService) PrepareHMACMessage(size int, payload ...string) []byte {
-	payloadlen := len(payload)
-	if size == 0 || payloadlen == 0 {
-		return nil
+// signerIdentity returns the first identity name of the signer with its key fingerprint
+func signerIdentity(signer *openpgp.Entity) string {
+	fingerprint := signerFingerprint(signer)
+
+	for _, identity := range signer.Identities {
+		return identity.Name + " (" + fingerprint + ")"
 	}
 
-	message := make([]byte, 0, size)
-	for i, chunk := range payload {
-		message = append(message, futils.UnsafeBytes(chunk)...)
+	return fingerprint
+}
 
-		if i+1 <= payloadlen {
-			message = append(message, byte(':'))
-		}
+func signerFingerprint(signer *openpgp.Entity) string {
+	return strings.ToUpper(hex.EncodeToString(signer.PrimaryKey.Fingerprint))
+}
+
+// signersIdentity joins identities of all counted signers
+func signersIdentity(signers openpgp.EntityList) string {
+	identities := make([]string, 0, len(signers))
+	for _, signer := range signers {
+		identities = append(identities, signerIdentity(signer))
 	}
 
-	return message
+	return strings.Join(identities, ", ")
+}
+
+func signersFingerprints(signers openpgp.EntityList) (fingerprints []string) {
+	for _, signer := range signers {
+		fingerprints = append(fingerprints, signerFingerprint(signer))
+	}
+
+	return
+}
+
+// RequestHMACMessage returns the message signed by clients for v1 api requests;
+// note the trailing colon after the last chunk
+func RequestHMACMessage(ip, path, hostname string) []byte {
+	return prepareHMACMessage(len(ip)+len(path)+len(hostname)+3, ip, path, hostname)
+}
+
+// SignRequest returns the hex encoded sign of v1 api request; it's the reference
+// implementation for clients and it shares the message format with the service
+func SignRequest(token, ip, path, hostname string) (_ string, e error) {
+	var sum []byte
+	if sum, e = hmacSum(token, RequestHMACMessage(ip, path, hostname), nil); e != nil {
+		return
+	}
+
+	return hex.EncodeToString(sum), e
 }
 
-func (m *AuthService) VerifyHMACSign(message, signed []byte) (string, bool) {
+// IsHMACEnabled reports whether the shared token is configured; hosts with
+// ed25519 keys only are served without it
+func (m *AuthService) IsHMACEnabled() bool {
+	return m.token != ""
+}
+
+func (m *AuthService) VerifyHMACSign(message, signed []byte) bool {
+	if !m.IsHMACEnabled() {
+		return false
+	}
+
 	var buf256 [sha256.Size]byte
 
 	buf, elen :=
 		bytes.NewBuffer(buf256[:]),
 		hex.EncodedLen(sha256.Size)
-	mac := hmac.New(sha256.New, futils.UnsafeBytes(m.token))
 
 	buf.Reset()
 	buf.Grow(elen)
@@ -68,12 +185,44 @@ func (m *AuthService) VerifyHMACSign(message, signed []byte) (string, bool) {
 		buf.WriteByte(0)
 	}
 
-	if _, e := mac.Write(message); e != nil {
+	sum, e := hmacSum(m.token, message, buf256[:0])
+	if e != nil {
 		m.log.Error().Msg("an error occurred while writing in hmac buffer, " + e.Error())
-		return "", false
+		return false
 	}
 
 	// todo save all buffers for reusing
-	hex.Encode(buf.Bytes(), mac.Sum(buf256[:0]))
-	return buf.String(), bytes.Equal(buf.Bytes(), signed)
+	hex.Encode(buf.Bytes(), sum)
+	return hmac.Equal(buf.Bytes(), signed)
+}
+
+//
+//
+//
+
+func prepareHMACMessage(size int, payload ...string) []byte {
+	payloadlen := len(payload)
+	if size == 0 || payloadlen == 0 {
+		return nil
+	}
+
+	message := make([]byte, 0, size)
+	for i, chunk := range payload {
+		message = append(message, futils.UnsafeBytes(chunk)...)
+
+		if i+1 <= payloadlen {
+			message = append(message, byte(':'))
+		}
+	}
+
+	return message
+}
+
+func hmacSum(token string, message, buf []byte) (_ []byte, e error) {
+	mac := hmac.New(sha256.New, futils.UnsafeBytes(token))
+	if _, e = mac.Write(message); e != nil {
+		return
+	}
+
+	return mac.Sum(buf), e
 }
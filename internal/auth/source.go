@@ -0,0 +1,333 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/MindHunter86/asmas/internal/gclient"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+)
+
+type (
+	// ConfigSource is a storage of the signed authorization config;
+	// the received payload is verified and parsed by AuthService
+	ConfigSource interface {
+		FetchConfig() (*ConfigPayload, error)
+		String() string
+	}
+
+	// committableSource is implemented by sources with conditional requests;
+	// Commit is called after the payload is verified and applied
+	committableSource interface {
+		Commit(payload *ConfigPayload)
+	}
+	ConfigPayload struct {
+		Name    string
+		Sha     string
+		Content []byte
+
+		// Signer and Fingerprint are filled after signature verification
+		Signer      string
+		Fingerprint string
+
+		// Commit is the last commit touching the config, nil if unknown
+		Commit *ConfigCommit
+
+		// serial, issued_at, file and digest of the verified yaml, they are filled by loadConfigPart
+		serial   uint64
+		issuedat time.Time
+		file     string
+		digest   string
+
+		// entity tag of the source response
+		etag []byte
+
+		// Parts are signed files of the config directory, they are verified independently
+		// and merged; Content is empty in this case
+		Parts []*ConfigPayload
+	}
+)
+
+// files of the config directory which are merged in the authorization list
+const configDirSuffix = ".yaml.asc"
+
+// ErrConfigNotModified is returned by sources which are able to detect unchanged configs
+// without downloading them
+var ErrConfigNotModified = errors.New("config has not been modified since the last fetch")
+
+const (
+	SourceGithub = "github"
+	SourceGitlab = "gitlab"
+	SourceGitea  = "gitea"
+	SourceURL    = "url"
+	SourceFile   = "file"
+	SourceGit    = "git"
+)
+
+func NewConfigSource(cc *cli.Context, log *zerolog.Logger) (ConfigSource, error) {
+	switch cc.String("auth-source") {
+	case SourceGithub:
+		client, e := gclient.NewHttpClient(cc, log)
+		if e != nil {
+			return nil, errors.New("could not initialize github client, check github-* and auth-github-* flags, " + e.Error())
+		}
+
+		return &githubSource{client: client, blobs: make(map[string][]byte), log: log}, nil
+	case SourceGitlab:
+		return newForgeSource(cc, log, gclient.FORGE_GITLAB)
+	case SourceGitea:
+		return newForgeSource(cc, log, gclient.FORGE_GITEA)
+	case SourceURL:
+		return newForgeSource(cc, log, gclient.FORGE_RAW)
+	case SourceFile:
+		if cc.String("auth-source-file") == "" {
+			return nil, errors.New("file config source requires auth-source-file flag")
+		}
+
+		return &fileSource{path: cc.String("auth-source-file")}, nil
+	case SourceGit:
+		if cc.String("auth-source-git-dir") == "" {
+			return nil, errors.New("git config source requires auth-source-git-dir flag")
+		}
+
+		return &gitSource{
+			dir:    cc.String("auth-source-git-dir"),
+			path:   cc.String("auth-github-path"),
+			branch: cc.String("auth-github-branch"),
+			pull:   cc.Bool("auth-source-git-pull"),
+		}, nil
+	default:
+		return nil, errors.New("unknown config source " + cc.String("auth-source"))
+	}
+}
+
+//
+//
+//
+
+type githubSource struct {
+	client *gclient.HttpClient
+
+	// contents of the config directory files by blob sha, unchanged files are not downloaded again
+	blobs map[string][]byte
+
+	log *zerolog.Logger
+}
+
+func (m *githubSource) FetchConfig() (_ *ConfigPayload, e error) {
+	var response *gclient.GithubResponse
+	if response, e = m.client.FetchConfigFromGithub(); errors.Is(e, gclient.ErrNotModified) {
+		return nil, ErrConfigNotModified
+	} else if e != nil {
+		return
+	}
+
+	if e = m.client.ValidateGithubResponse(response); e != nil {
+		return
+	}
+
+	var payload *ConfigPayload
+	if response.Type == gclient.GITHUB_TYPE_DIR {
+		if payload, e = m.fetchDirectory(response); e != nil {
+			return
+		}
+	} else {
+		payload = &ConfigPayload{
+			Name:    response.Name,
+			Sha:     response.Sha,
+			Content: response.Content,
+		}
+	}
+
+	payload.Commit, payload.etag = m.fetchCommit(), response.ETag
+	return payload, e
+}
+
+func (m *githubSource) Commit(payload *ConfigPayload) {
+	m.client.CommitETag(payload.etag)
+}
+
+// fetchCommit returns the last commit touching the config path; the commit is informational,
+// so lookup errors are not fatal
+func (m *githubSource) fetchCommit() *ConfigCommit {
+	commit, e := m.client.FetchLastCommit()
+	if e != nil {
+		m.log.Warn().Msg("could not fetch the last commit of authorization config, " + e.Error())
+		return nil
+	}
+
+	return &ConfigCommit{
+		Sha:    commit.Sha,
+		Author: commit.AuthorIdentity(),
+		Time:   commit.CommitTime(),
+	}
+}
+
+// fetchDirectory downloads signed configs of the directory listing sorted by name;
+// the directory hash is made of file names and blob hashes
+func (m *githubSource) fetchDirectory(listing *gclient.GithubResponse) (_ *ConfigPayload, e error) {
+	payload := &ConfigPayload{Name: listing.Name}
+	blobs, hash := make(map[string][]byte), sha256.New()
+
+	entries := make([]*gclient.GithubResponse, 0, len(listing.Entries))
+	for _, entry := range listing.Entries {
+		if entry.Type == gclient.GITHUB_TYPE_FILE && strings.HasSuffix(entry.Name, configDirSuffix) {
+			entries = append(entries, entry)
+		}
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	if len(entries) == 0 {
+		return nil, errors.New("there are no *" + configDirSuffix + " files in config directory " + listing.Path)
+	}
+
+	for _, entry := range entries {
+		content, ok := m.blobs[entry.Sha]
+		if !ok {
+			var response *gclient.GithubResponse
+			if response, e = m.client.FetchFileFromGithub(entry.Path); e != nil {
+				return nil, fmt.Errorf("could not fetch config file %s, %s", entry.Path, e.Error())
+			} else if response.Sha != entry.Sha {
+				return nil, fmt.Errorf("config file %s has been changed while fetching, hash %s is expected", entry.Path, entry.Sha)
+			}
+
+			content = response.Content
+		}
+
+		blobs[entry.Sha] = content
+		fmt.Fprintf(hash, "%s:%s\n", entry.Name, entry.Sha)
+
+		payload.Parts = append(payload.Parts, &ConfigPayload{Name: entry.Name, Sha: entry.Sha, Content: content})
+	}
+
+	m.blobs, payload.Sha = blobs, hex.EncodeToString(hash.Sum(nil))
+	return payload, e
+}
+
+func (*githubSource) String() string { return SourceGithub }
+
+type forgeSource struct {
+	kind   string
+	client *gclient.ForgeClient
+}
+
+// newForgeSource returns untyped nil on errors, so the service never gets the source without the client
+func newForgeSource(cc *cli.Context, log *zerolog.Logger, kind gclient.ForgeKind) (_ ConfigSource, e error) {
+	source := &forgeSource{kind: cc.String("auth-source")}
+	if source.client, e = gclient.NewForgeClient(cc, log, kind); e != nil {
+		return nil, errors.New("could not initialize " + source.kind + " client, check auth-source-* flags, " + e.Error())
+	}
+
+	return source, e
+}
+
+func (m *forgeSource) FetchConfig() (_ *ConfigPayload, e error) {
+	payload := &ConfigPayload{}
+	payload.Name, payload.Sha, payload.Content, e = m.client.FetchConfig()
+	return payload, e
+}
+
+func (m *forgeSource) String() string { return m.kind }
+
+type fileSource struct {
+	path string
+}
+
+func (m *fileSource) FetchConfig() (_ *ConfigPayload, e error) {
+	var content []byte
+	if content, e = os.ReadFile(m.path); e != nil {
+		return
+	}
+
+	hash := sha256.Sum256(content)
+	return &ConfigPayload{
+		Name:    filepath.Base(m.path),
+		Sha:     hex.EncodeToString(hash[:]),
+		Content: content,
+	}, e
+}
+
+func (*fileSource) String() string { return SourceFile }
+
+type gitSource struct {
+	dir, path, branch string
+	pull              bool
+}
+
+func (m *gitSource) FetchConfig() (_ *ConfigPayload, e error) {
+	if m.pull {
+		if _, e = m.git("pull", "--ff-only", "--quiet"); e != nil {
+			return
+		}
+	}
+
+	var sha []byte
+	if sha, e = m.git("rev-parse", "--verify", m.branch+":"+m.path); e != nil {
+		return
+	}
+	sha = bytes.TrimSpace(sha)
+
+	var content []byte
+	if content, e = m.git("cat-file", "blob", string(sha)); e != nil {
+		return
+	}
+
+	var commit *ConfigCommit
+	if commit, e = m.lastCommit(); e != nil {
+		return
+	}
+
+	return &ConfigPayload{
+		Name:    filepath.Base(m.path),
+		Sha:     string(sha),
+		Content: content,
+		Commit:  commit,
+	}, e
+}
+
+// lastCommit returns the last commit of the branch touching the config path
+func (m *gitSource) lastCommit() (_ *ConfigCommit, e error) {
+	var output []byte
+	if output, e = m.git("log", "-1", "--format=%H%x00%an <%ae>%x00%cI", m.branch, "--", m.path); e != nil {
+		return
+	}
+
+	fields := strings.Split(string(bytes.TrimSpace(output)), "\x00")
+	if len(fields) != 3 {
+		return nil, errors.New("unexpected git log output for config path " + m.path)
+	}
+
+	commit := &ConfigCommit{Sha: fields[0], Author: fields[1]}
+	if commit.Time, e = time.Parse(time.RFC3339, fields[2]); e != nil {
+		return
+	}
+
+	return commit, e
+}
+
+func (*gitSource) String() string { return SourceGit }
+
+func (m *gitSource) git(args ...string) (_ []byte, e error) {
+	var stderr bytes.Buffer
+
+	cmd := exec.Command("git", append([]string{"-C", m.dir}, args...)...)
+	cmd.Stderr = &stderr
+
+	var output []byte
+	if output, e = cmd.Output(); e != nil {
+		return nil, errors.New("git " + args[0] + " failed, " + e.Error() + "; " +
+			strings.TrimSpace(stderr.String()))
+	}
+
+	return output, e
+}
@@ -0,0 +1,207 @@
+package auth
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// last-known-good signed payload, it's saved as is and verified again on loading;
+// files of the config directory are saved in stateConfigDir
+const (
+	stateConfigName = "config.yaml.asc"
+	stateConfigDir  = "config.d"
+)
+
+type ConfigStatus struct {
+	Source   string `json:"source"`
+	Sha      string `json:"sha,omitempty"`
+	Ready    bool   `json:"ready"`
+	Degraded bool   `json:"degraded"`
+	Age      string `json:"age,omitempty"`
+}
+
+func (m *AuthService) ConfigStatus() (status *ConfigStatus) {
+	status = &ConfigStatus{}
+	if m.source != nil {
+		status.Source = m.source.String()
+	}
+
+	actionWithRLock(&m.mu, func() {
+		status.Ready, status.Degraded, status.Sha = m.authlist != nil, m.degraded, m.appliedsha
+
+		if !m.appliedtime.IsZero() {
+			status.Age = time.Since(m.appliedtime).Round(time.Second).String()
+		}
+	})
+
+	return
+}
+
+//
+//
+//
+
+func (m *AuthService) saveConfigState(payload *ConfigPayload) (e error) {
+	if m.statedir == "" {
+		return
+	}
+
+	if len(payload.Parts) != 0 {
+		if e = m.saveConfigStateParts(payload.Parts); e != nil {
+			return
+		}
+
+		return removeIfExists(filepath.Join(m.statedir, stateConfigName))
+	}
+
+	if e = writeStateFile(m.statedir, stateConfigName, payload.Content); e != nil {
+		return
+	}
+
+	return removeIfExists(filepath.Join(m.statedir, stateConfigDir))
+}
+
+func (m *AuthService) touchConfigState() (e error) {
+	if m.statedir == "" {
+		return
+	}
+
+	now := time.Now()
+	for _, name := range []string{stateConfigDir, stateConfigName} {
+		if e = os.Chtimes(filepath.Join(m.statedir, name), now, now); !errors.Is(e, os.ErrNotExist) {
+			return
+		}
+	}
+
+	return nil
+}
+
+func (m *AuthService) loadConfigState() (_ *ConfigPayload, _ time.Time, e error) {
+	if m.statedir == "" {
+		return nil, time.Time{}, errors.New("state directory is not defined, last-known-good config is unavailable")
+	}
+
+	path := filepath.Join(m.statedir, stateConfigDir)
+
+	var fdinfo os.FileInfo
+	if fdinfo, e = os.Stat(path); errors.Is(e, os.ErrNotExist) {
+		path = filepath.Join(m.statedir, stateConfigName)
+		fdinfo, e = os.Stat(path)
+	}
+
+	if e != nil {
+		return
+	}
+
+	if m.statemaxage != 0 && time.Since(fdinfo.ModTime()) > m.statemaxage {
+		return nil, time.Time{}, errors.New("last-known-good config is older than allowed by auth-state-max-age, " +
+			time.Since(fdinfo.ModTime()).Round(time.Second).String())
+	}
+
+	if !fdinfo.IsDir() {
+		var payload *ConfigPayload
+		payload, e = readStateFile(path)
+		return payload, fdinfo.ModTime(), e
+	}
+
+	var entries []os.DirEntry
+	if entries, e = os.ReadDir(path); e != nil {
+		return
+	}
+
+	payload, hash := &ConfigPayload{Name: stateConfigDir}, sha256.New()
+	for _, entry := range entries {
+		var part *ConfigPayload
+		if part, e = readStateFile(filepath.Join(path, entry.Name())); e != nil {
+			return
+		}
+
+		hash.Write([]byte(part.Name + ":" + part.Sha + "\n"))
+		payload.Parts = append(payload.Parts, part)
+	}
+
+	if len(payload.Parts) == 0 {
+		return nil, time.Time{}, errors.New("last-known-good config directory is empty")
+	}
+
+	payload.Sha = hex.EncodeToString(hash.Sum(nil))
+	return payload, fdinfo.ModTime(), e
+}
+
+func (m *AuthService) isStateTooOld() bool {
+	return m.degraded && m.statemaxage != 0 && time.Since(m.appliedtime) > m.statemaxage
+}
+
+// saveConfigStateParts writes files in the temporary directory and swaps it with the current one
+func (m *AuthService) saveConfigStateParts(parts []*ConfigPayload) (e error) {
+	var tmpdir string
+	if tmpdir, e = os.MkdirTemp(m.statedir, stateConfigDir+".*"); e != nil {
+		return
+	}
+	defer os.RemoveAll(tmpdir)
+
+	for _, part := range parts {
+		if e = writeStateFile(tmpdir, filepath.Base(part.Name), part.Content); e != nil {
+			return
+		}
+	}
+
+	path := filepath.Join(m.statedir, stateConfigDir)
+	if e = os.Rename(path, tmpdir+".old"); e != nil && !errors.Is(e, os.ErrNotExist) {
+		return
+	}
+	defer os.RemoveAll(tmpdir + ".old")
+
+	return os.Rename(tmpdir, path)
+}
+
+// writeStateFile writes and renames the file for avoiding partially written state files
+func writeStateFile(dir, name string, content []byte) (e error) {
+	var fd *os.File
+	if fd, e = os.CreateTemp(dir, name+".*"); e != nil {
+		return
+	}
+	defer os.Remove(fd.Name())
+
+	if _, e = fd.Write(content); e != nil {
+		fd.Close()
+		return
+	}
+
+	if e = fd.Sync(); e != nil {
+		fd.Close()
+		return
+	}
+
+	if e = fd.Close(); e != nil {
+		return
+	}
+
+	return os.Rename(fd.Name(), filepath.Join(dir, name))
+}
+
+func readStateFile(path string) (_ *ConfigPayload, e error) {
+	var content []byte
+	if content, e = os.ReadFile(path); e != nil {
+		return
+	}
+
+	hash := sha256.Sum256(content)
+	return &ConfigPayload{
+		Name:    filepath.Base(path),
+		Sha:     hex.EncodeToString(hash[:]),
+		Content: content,
+	}, e
+}
+
+func removeIfExists(path string) (e error) {
+	if e = os.RemoveAll(path); errors.Is(e, os.ErrNotExist) {
+		return nil
+	}
+
+	return
+}
@@ -16,7 +16,14 @@ func actionWithLock(mu *sync.RWMutex, action func()) {
 	action()
 }
 
-func actionReturbableWithRLock[V bool](mu *sync.RWMutex, action func() V) V {
+func actionWithRLock(mu *sync.RWMutex, action func()) {
+	mu.RLock()
+	defer mu.RUnlock()
+
+	action()
+}
+
+func actionReturbableWithRLock[V any](mu *sync.RWMutex, action func() V) V {
 	mu.RLock()
 	defer mu.RUnlock()
 
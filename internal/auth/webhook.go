@@ -0,0 +1,57 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"github.com/MindHunter86/asmas/internal/gclient"
+	futils "github.com/gofiber/fiber/v2/utils"
+)
+
+const webhookSignPrefix = "sha256="
+
+func (m *AuthService) IsWebhookEnabled() bool {
+	return m.hooksecret != ""
+}
+
+// VerifyWebhookSign checks X-Hub-Signature-256 header value of github webhook delivery
+func (m *AuthService) VerifyWebhookSign(payload []byte, signature string) bool {
+	if !strings.HasPrefix(signature, webhookSignPrefix) {
+		return false
+	}
+
+	var expected [sha256.Size]byte
+	if n, e := hex.Decode(expected[:], futils.UnsafeBytes(signature[len(webhookSignPrefix):])); e != nil || n != sha256.Size {
+		return false
+	}
+
+	mac := hmac.New(sha256.New, futils.UnsafeBytes(m.hooksecret))
+	mac.Write(payload)
+
+	return hmac.Equal(mac.Sum(nil), expected[:])
+}
+
+// IsConfigPush reports whether the push event changes the authorization config
+func (m *AuthService) IsConfigPush(event *gclient.GithubPushEvent) bool {
+	if event.Repository == nil || !strings.EqualFold(event.Repository.FullName, m.hookrepo) {
+		return false
+	}
+
+	if event.Ref != "refs/heads/"+m.hookbranch {
+		return false
+	}
+
+	return event.Touches(m.hookpath)
+}
+
+// TriggerUpdate schedules an immediate authorization list update;
+// triggers received before the update are coalesced into one
+func (m *AuthService) TriggerUpdate() {
+	select {
+	case m.trigger <- struct{}{}:
+	default:
+		m.log.Debug().Msg("authorization list update has been already triggered, skipping")
+	}
+}
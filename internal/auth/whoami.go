@@ -0,0 +1,72 @@
+package auth
+
+import (
+	"errors"
+	"net/netip"
+	"sort"
+	"time"
+)
+
+type (
+	// WhoamiInfo describes what the requesting host is allowed to fetch
+	WhoamiInfo struct {
+		Hostname  string         `json:"hostname"`
+		ClientIP  string         `json:"client_ip"`
+		ConfigSha string         `json:"config_sha"`
+		Entries   []*WhoamiEntry `json:"entries"`
+	}
+	// WhoamiEntry is the authorization entry matched by the hostname;
+	// Scopes are filled only if the entry is available for the client
+	WhoamiEntry struct {
+		Name   string   `json:"name"`
+		Result string   `json:"result"`
+		Scopes []string `json:"scopes,omitempty"`
+	}
+)
+
+// Whoami returns authorization entries matched by the hostname with the same checks
+// as Authorize does, entries with mismatched hostname are skipped
+func (m *AuthService) Whoami(hostname, ip string) (_ *WhoamiInfo, e error) {
+	if !m.isApiReady() {
+		return nil, errors.New("auth service api is not ready yet")
+	}
+
+	var addr netip.Addr
+	if addr, e = netip.ParseAddr(ip); e != nil {
+		return nil, errors.New("could not parse client ip, " + e.Error())
+	}
+
+	info := &WhoamiInfo{Hostname: hostname, ClientIP: ip, Entries: make([]*WhoamiEntry, 0)}
+
+	actionWithRLock(&m.mu, func() {
+		info.ConfigSha, e = m.appliedsha, nil
+		if m.isStateTooOld() {
+			e = errors.New("last-known-good authorization config is too old, refusing to use it")
+			return
+		}
+
+		now := time.Now()
+		for _, auth := range m.authlist.AuthorizationList {
+			result := auth.matchFqdn(hostname, now)
+			if result == AUTHZ_HOSTNAME_MISMATCH {
+				continue
+			}
+
+			if result == AUTHZ_ALLOWED && !auth.isAuthorizedAddr(addr) {
+				result = AUTHZ_NETWORK_MISMATCH
+			}
+
+			entry := &WhoamiEntry{Name: auth.Name, Result: result.String()}
+			if result == AUTHZ_ALLOWED {
+				for scope := range auth.scopes {
+					entry.Scopes = append(entry.Scopes, string(scope))
+				}
+				sort.Strings(entry.Scopes)
+			}
+
+			info.Entries = append(info.Entries, entry)
+		}
+	})
+
+	return info, e
+}
@@ -0,0 +1,70 @@
+package gclient
+
+import (
+	"bytes"
+	"crypto/sha1" // skipcq: GSC-G505 git object ids, not a security primitive
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"strconv"
+
+	"github.com/valyala/fasthttp"
+)
+
+// the contents api embeds files up to 1 MB only; larger files are returned
+// with empty content and "none" encoding and must be fetched from the blobs api
+const githubRawMediaType = "application/vnd.github.raw"
+
+// completeContent downloads the content of large files via the git blobs api
+func (m *HttpClient) completeContent(response *GithubResponse) (e error) {
+	if response.Type != GITHUB_TYPE_FILE || len(response.Content) != 0 || response.Size == 0 {
+		return
+	}
+
+	if response.Size > m.maxsize {
+		return fmt.Errorf("config file %s size %d exceeds github-max-config-size %d", response.Path, response.Size, m.maxsize)
+	}
+
+	m.log.Info().Msgf("config file %s is too large for the contents api (%d bytes), fetching it from the blobs api",
+		response.Path, response.Size)
+
+	req, rsp := m.acquireApiRequestResponse(fmt.Sprintf("/repos/%s/git/blobs/%s", m.githubrepo, response.Sha))
+	defer m.releaseRequestResponse(req, rsp)
+
+	req.Header.Set(fasthttp.HeaderAccept, githubRawMediaType)
+
+	// the body is streamed and never buffered beyond the cap
+	rsp.StreamBody = true
+	if e = m.doAuthorized(req, rsp); e != nil {
+		return
+	}
+
+	var buf bytes.Buffer
+	buf.Grow(response.Size)
+
+	var n int64
+	if n, e = io.Copy(&buf, io.LimitReader(rsp.BodyStream(), int64(m.maxsize)+1)); e != nil {
+		return
+	} else if n > int64(m.maxsize) {
+		return fmt.Errorf("blob of config file %s exceeds github-max-config-size %d", response.Path, m.maxsize)
+	}
+
+	response.Content = buf.Bytes()
+	return
+}
+
+// gitBlobHash returns the git object id of the content, sha-1 or sha-256 repositories
+// are detected by the expected hash length
+func gitBlobHash(content []byte, expected string) string {
+	var hasher hash.Hash = sha1.New()
+	if len(expected) == hex.EncodedLen(sha256.Size) {
+		hasher = sha256.New()
+	}
+
+	hasher.Write([]byte("blob " + strconv.Itoa(len(content)) + "\x00"))
+	hasher.Write(content)
+
+	return hex.EncodeToString(hasher.Sum(nil))
+}
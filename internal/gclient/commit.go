@@ -0,0 +1,72 @@
+package gclient
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/mailru/easyjson"
+)
+
+type (
+	//easyjson:json
+	GithubRepoCommit struct {
+		Sha    string                  `json:"sha"`
+		Commit *GithubRepoCommitDetail `json:"commit"`
+	}
+	GithubRepoCommitDetail struct {
+		Author    *GithubRepoCommitAuthor `json:"author"`
+		Committer *GithubRepoCommitAuthor `json:"committer"`
+	}
+	GithubRepoCommitAuthor struct {
+		Name  string    `json:"name"`
+		Email string    `json:"email"`
+		Date  time.Time `json:"date"`
+	}
+
+	//easyjson:json
+	GithubRepoCommits []*GithubRepoCommit
+)
+
+// FetchLastCommit returns the last commit of auth-github-branch touching auth-github-path
+func (m *HttpClient) FetchLastCommit() (_ *GithubRepoCommit, e error) {
+	req, rsp := m.acquireApiRequestResponse(fmt.Sprintf("/repos/%s/commits?sha=%s&path=%s&per_page=1",
+		m.githubrepo, url.QueryEscape(m.githubbranch), url.QueryEscape(m.githubpath)))
+	defer m.releaseRequestResponse(req, rsp)
+
+	if e = m.doAuthorized(req, rsp); e != nil {
+		return
+	}
+
+	var commits GithubRepoCommits
+	if e = easyjson.Unmarshal(rsp.Body(), &commits); e != nil {
+		return
+	}
+
+	if len(commits) == 0 || commits[0] == nil || commits[0].Commit == nil {
+		return nil, errors.New("there are no commits touching " + m.githubpath + " in branch " + m.githubbranch)
+	}
+
+	return commits[0], e
+}
+
+// AuthorIdentity returns the commit author formatted as "Name <email>"
+func (m *GithubRepoCommit) AuthorIdentity() string {
+	if m.Commit.Author == nil {
+		return ""
+	}
+
+	return m.Commit.Author.Name + " <" + m.Commit.Author.Email + ">"
+}
+
+// CommitTime returns the committer date, it's the time of the change in the branch
+func (m *GithubRepoCommit) CommitTime() time.Time {
+	if m.Commit.Committer != nil {
+		return m.Commit.Committer.Date
+	} else if m.Commit.Author != nil {
+		return m.Commit.Author.Date
+	}
+
+	return time.Time{}
+}
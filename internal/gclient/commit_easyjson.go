@@ -0,0 +1,313 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package gclient
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient(in *jlexer.Lexer, out *GithubRepoCommits) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(GithubRepoCommits, 0, 8)
+			} else {
+				*out = GithubRepoCommits{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v1 *GithubRepoCommit
+			if in.IsNull() {
+				in.Skip()
+				v1 = nil
+			} else {
+				if v1 == nil {
+					v1 = new(GithubRepoCommit)
+				}
+				(*v1).UnmarshalEasyJSON(in)
+			}
+			*out = append(*out, v1)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient(out *jwriter.Writer, in GithubRepoCommits) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v2, v3 := range in {
+			if v2 > 0 {
+				out.RawByte(',')
+			}
+			if v3 == nil {
+				out.RawString("null")
+			} else {
+				(*v3).MarshalEasyJSON(out)
+			}
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GithubRepoCommits) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GithubRepoCommits) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GithubRepoCommits) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GithubRepoCommits) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient(l, v)
+}
+func easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient1(in *jlexer.Lexer, out *GithubRepoCommit) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "sha":
+			out.Sha = string(in.String())
+		case "commit":
+			if in.IsNull() {
+				in.Skip()
+				out.Commit = nil
+			} else {
+				if out.Commit == nil {
+					out.Commit = new(GithubRepoCommitDetail)
+				}
+				easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient2(in, out.Commit)
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient1(out *jwriter.Writer, in GithubRepoCommit) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"sha\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Sha))
+	}
+	{
+		const prefix string = ",\"commit\":"
+		out.RawString(prefix)
+		if in.Commit == nil {
+			out.RawString("null")
+		} else {
+			easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient2(out, *in.Commit)
+		}
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GithubRepoCommit) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GithubRepoCommit) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GithubRepoCommit) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GithubRepoCommit) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient1(l, v)
+}
+func easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient2(in *jlexer.Lexer, out *GithubRepoCommitDetail) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "author":
+			if in.IsNull() {
+				in.Skip()
+				out.Author = nil
+			} else {
+				if out.Author == nil {
+					out.Author = new(GithubRepoCommitAuthor)
+				}
+				easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient3(in, out.Author)
+			}
+		case "committer":
+			if in.IsNull() {
+				in.Skip()
+				out.Committer = nil
+			} else {
+				if out.Committer == nil {
+					out.Committer = new(GithubRepoCommitAuthor)
+				}
+				easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient3(in, out.Committer)
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient2(out *jwriter.Writer, in GithubRepoCommitDetail) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"author\":"
+		out.RawString(prefix[1:])
+		if in.Author == nil {
+			out.RawString("null")
+		} else {
+			easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient3(out, *in.Author)
+		}
+	}
+	{
+		const prefix string = ",\"committer\":"
+		out.RawString(prefix)
+		if in.Committer == nil {
+			out.RawString("null")
+		} else {
+			easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient3(out, *in.Committer)
+		}
+	}
+	out.RawByte('}')
+}
+func easyjson6efd7357DecodeGithubComMindHunter86AsmasInternalGclient3(in *jlexer.Lexer, out *GithubRepoCommitAuthor) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "name":
+			out.Name = string(in.String())
+		case "email":
+			out.Email = string(in.String())
+		case "date":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.Date).UnmarshalJSON(data))
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson6efd7357EncodeGithubComMindHunter86AsmasInternalGclient3(out *jwriter.Writer, in GithubRepoCommitAuthor) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"name\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Name))
+	}
+	{
+		const prefix string = ",\"email\":"
+		out.RawString(prefix)
+		out.String(string(in.Email))
+	}
+	{
+		const prefix string = ",\"date\":"
+		out.RawString(prefix)
+		out.Raw((in.Date).MarshalJSON())
+	}
+	out.RawByte('}')
+}
@@ -0,0 +1,217 @@
+package gclient
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/MindHunter86/asmas/internal/utils"
+	"github.com/mailru/easyjson"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+	"github.com/valyala/fasthttp"
+)
+
+type ForgeKind uint8
+
+const (
+	FORGE_RAW ForgeKind = iota
+	FORGE_GITLAB
+	FORGE_GITEA
+)
+
+type (
+	// ForgeClient fetches the signed config from any git forge which is not github
+	// or from an arbitrary https url
+	ForgeClient struct {
+		*fasthttp.HostClient
+
+		kind  ForgeKind
+		uri   string
+		host  string
+		token string
+
+		log *zerolog.Logger
+	}
+
+	//easyjson:json
+	GitlabResponse struct {
+		// OK response
+		FileName string `json:"file_name,omitempty"`
+		Size     int    `json:"size,omitempty"`
+		Encoding string `json:"encoding,omitempty"`
+		Content  []byte `json:"content,omitempty"`
+		BlobId   string `json:"blob_id,omitempty"`
+
+		// Error response
+		Message string `json:"message,omitempty"`
+	}
+)
+
+func NewForgeClient(cc *cli.Context, log *zerolog.Logger, kind ForgeKind) (_ *ForgeClient, e error) {
+	var rawurl string
+	switch kind {
+	case FORGE_RAW:
+		rawurl = cc.String("auth-source-url")
+	case FORGE_GITLAB:
+		rawurl = fmt.Sprintf("%s/api/v4/projects/%s/repository/files/%s?ref=%s",
+			strings.TrimSuffix(cc.String("auth-source-forge-url"), "/"),
+			url.PathEscape(cc.String("auth-github-repo")),
+			url.PathEscape(cc.String("auth-github-path")),
+			url.QueryEscape(cc.String("auth-github-branch")))
+	case FORGE_GITEA:
+		rawurl = fmt.Sprintf("%s/api/v1/repos/%s/contents/%s?ref=%s",
+			strings.TrimSuffix(cc.String("auth-source-forge-url"), "/"),
+			cc.String("auth-github-repo"),
+			cc.String("auth-github-path"),
+			url.QueryEscape(cc.String("auth-github-branch")))
+	default:
+		return nil, errors.New("BUG! undefined forge kind received")
+	}
+
+	var furl *url.URL
+	var addr string
+	if furl, addr, e = parseBaseURL(rawurl); e != nil {
+		return
+	}
+
+	var hclient *fasthttp.HostClient
+	if hclient, e = newHostClient(cc, log, addr, furl.Scheme == "https"); e != nil {
+		return
+	}
+
+	client := &ForgeClient{
+		HostClient: hclient,
+
+		kind:  kind,
+		uri:   rawurl,
+		host:  furl.Host,
+		token: cc.String("auth-source-forge-token"),
+
+		log: log,
+	}
+
+	// gitlab requires url-encoded slashes in the project and file path
+	client.DisablePathNormalizing = true
+
+	return client, e
+}
+
+// FetchConfig returns the name, the content hash and the content of the config file
+func (m *ForgeClient) FetchConfig() (name, sha string, content []byte, e error) {
+	req, rsp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer func() {
+		fasthttp.ReleaseRequest(req)
+		fasthttp.ReleaseResponse(rsp)
+	}()
+
+	m.prepareRequest(req)
+
+	if e = m.Do(req, rsp); e != nil {
+		return
+	}
+
+	if zerolog.GlobalLevel() <= zerolog.DebugLevel {
+		m.log.Trace().Msg(req.String())
+		m.log.Trace().Msg(rsp.String())
+	}
+
+	status, body := rsp.StatusCode(), rsp.Body()
+	if status != fasthttp.StatusOK {
+		e = fmt.Errorf("config source respond with an unexpected status %d", status)
+		return
+	}
+
+	if utils.IsEmpty(body) {
+		e = errors.New("config source respond with an empty body, unexpected result")
+		return
+	}
+
+	switch m.kind {
+	case FORGE_RAW:
+		hash := sha256.Sum256(body)
+		return m.uri, hex.EncodeToString(hash[:]), append([]byte(nil), body...), e
+	case FORGE_GITLAB:
+		response := &GitlabResponse{}
+		if e = easyjson.Unmarshal(body, response); e != nil {
+			return
+		}
+
+		if e = m.validateGitlabResponse(response); e != nil {
+			return
+		}
+
+		return response.FileName, response.BlobId, response.Content, e
+	case FORGE_GITEA:
+		// gitea contents api is compatible with github one
+		response := &GithubResponse{}
+		if e = easyjson.Unmarshal(body, response); e != nil {
+			return
+		}
+
+		if e = validateContentsResponse(m.log, response); e != nil {
+			return
+		}
+
+		return response.Name, response.Sha, response.Content, e
+	}
+
+	e = errors.New("BUG! undefined forge kind received")
+	return
+}
+
+//
+//
+//
+
+func (m *ForgeClient) prepareRequest(req *fasthttp.Request) {
+	req.SetRequestURI(m.uri)
+	req.URI().DisablePathNormalizing = true
+
+	req.Header.Set(fasthttp.HeaderUserAgent, m.Name)
+	req.Header.Set(fasthttp.HeaderConnection, "keep-alive")
+	req.Header.Set(fasthttp.HeaderCacheControl, "no-cache")
+	req.Header.Set(fasthttp.HeaderPragma, "no-cache")
+
+	if m.kind != FORGE_RAW {
+		req.Header.Set(fasthttp.HeaderAccept, "application/json; charset=utf-8")
+	}
+
+	if m.token != "" {
+		switch m.kind {
+		case FORGE_GITLAB:
+			req.Header.Set("PRIVATE-TOKEN", m.token)
+		case FORGE_GITEA:
+			req.Header.Set(fasthttp.HeaderAuthorization, "token "+m.token)
+		case FORGE_RAW:
+			req.Header.Set(fasthttp.HeaderAuthorization, "Bearer "+m.token)
+		}
+	}
+
+	req.Header.SetHost(m.host)
+	req.UseHostHeader = true
+}
+
+func (m *ForgeClient) validateGitlabResponse(response *GitlabResponse) error {
+	if response.Message != "" {
+		m.log.Trace().Msgf("message: %s", response.Message)
+		return errors.New("response has message field, seems gitlab respond with an error")
+	}
+
+	if response.Encoding != "base64" {
+		m.log.Trace().Msgf("response encoding: %s; expecting 'base64'", response.Encoding)
+		return errors.New("unexpected response content encoding received")
+	}
+
+	if clen := len(response.Content); clen != response.Size {
+		m.log.Trace().Msgf("content len(): %d; reponse.size: %d", clen, response.Size)
+		return errors.New("response content length is not matches with responded size")
+	}
+
+	m.log.Info().Msgf("downloaded and validated file %s with hash %s and length %d",
+		response.FileName, response.BlobId, response.Size)
+	return nil
+}
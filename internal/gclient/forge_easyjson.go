@@ -0,0 +1,151 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package gclient
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonC36f617DecodeGithubComMindHunter86AsmasInternalGclient(in *jlexer.Lexer, out *GitlabResponse) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "file_name":
+			out.FileName = string(in.String())
+		case "size":
+			out.Size = int(in.Int())
+		case "encoding":
+			out.Encoding = string(in.String())
+		case "content":
+			if in.IsNull() {
+				in.Skip()
+				out.Content = nil
+			} else {
+				out.Content = in.Bytes()
+			}
+		case "blob_id":
+			out.BlobId = string(in.String())
+		case "message":
+			out.Message = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonC36f617EncodeGithubComMindHunter86AsmasInternalGclient(out *jwriter.Writer, in GitlabResponse) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	if in.FileName != "" {
+		const prefix string = ",\"file_name\":"
+		first = false
+		out.RawString(prefix[1:])
+		out.String(string(in.FileName))
+	}
+	if in.Size != 0 {
+		const prefix string = ",\"size\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Int(int(in.Size))
+	}
+	if in.Encoding != "" {
+		const prefix string = ",\"encoding\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Encoding))
+	}
+	if len(in.Content) != 0 {
+		const prefix string = ",\"content\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.Base64Bytes(in.Content)
+	}
+	if in.BlobId != "" {
+		const prefix string = ",\"blob_id\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.BlobId))
+	}
+	if in.Message != "" {
+		const prefix string = ",\"message\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Message))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GitlabResponse) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonC36f617EncodeGithubComMindHunter86AsmasInternalGclient(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GitlabResponse) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonC36f617EncodeGithubComMindHunter86AsmasInternalGclient(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GitlabResponse) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonC36f617DecodeGithubComMindHunter86AsmasInternalGclient(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GitlabResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonC36f617DecodeGithubComMindHunter86AsmasInternalGclient(l, v)
+}
@@ -3,14 +3,16 @@ package gclient
 import (
 	"bytes"
 	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
-	"strconv"
+	"net"
+	"net/url"
+	"os"
+	"path"
 	"strings"
-	"time"
 
 	"github.com/MindHunter86/asmas/internal/utils"
-	futils "github.com/gofiber/fiber/v2/utils"
 	"github.com/mailru/easyjson"
 	"github.com/rs/zerolog"
 	"github.com/urfave/cli/v2"
@@ -23,9 +25,25 @@ type (
 
 		githuburi    *fasthttp.URI
 		githubapiver string
+		githubrepo   string
+		githubpath   string
+		githubbranch string
 
-		rateremain int
-		ratereset  time.Time
+		// scheme, host and path prefix of the api, i.e. https://ghe.corp/api/v3
+		apibase string
+		apihost string
+
+		// nil authorizer means anonymous access with 60 requests per hour limit
+		authorizer githubAuthorizer
+
+		rate  rateState
+		retry *retryPolicy
+
+		// large files are fetched from the blobs api up to this size
+		maxsize int
+
+		// entity tag of the last received content; 304 responses are not counted by github limits
+		etag []byte
 
 		log *zerolog.Logger
 	}
@@ -34,6 +52,7 @@ type (
 	GithubResponse struct {
 		// OK response
 		Name    string `json:",omitempty"`
+		Path    string `json:",omitempty"`
 		Sha     string `json:",omitempty"`
 		Size    int    `json:",omitempty"`
 		Type    string `json:",omitempty"`
@@ -42,175 +61,370 @@ type (
 		// Error response
 		Message string `json:",omitempty"`
 		Status  int    `json:",omitempty"`
-	}
-)
 
-func NewHttpClient(cc *cli.Context, log *zerolog.Logger) *HttpClient {
-	rri, apihost := fasthttp.AcquireURI(), []byte(cc.String("github-api-addr"))
-	apiurl := fmt.Sprintf("https://%s/repos/%s/contents/%s?ref=%s",
-		cc.String("github-api-addr"),
-		cc.String("auth-github-repo"),
-		cc.String("auth-github-path"),
-		cc.String("auth-github-branch"))
+		// directory listing, contents are not included
+		Entries GithubDirectory `json:"-"`
 
-	if e := rri.Parse([]byte(apihost), []byte(apiurl)); e != nil {
-		return nil
+		// entity tag of the response, it's saved by CommitETag after the config is applied
+		ETag []byte `json:"-"`
 	}
 
-	return &HttpClient{
-		HostClient: &fasthttp.HostClient{
-			// https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/User-Agent#crawler_and_bot_ua_strings
-			Name: fmt.Sprintf("Mozilla/5.0 (compatible; %s/%s; +https://anilibria.top/support)",
-				cc.App.Name, cc.App.Version),
+	//easyjson:json
+	GithubDirectory []*GithubResponse
+)
 
-			Addr:  cc.String("github-api-addr"),
-			IsTLS: bytes.Equal(rri.Scheme(), []byte("https")),
+const (
+	GITHUB_TYPE_FILE = "file"
+	GITHUB_TYPE_DIR  = "dir"
+)
 
-			TLSConfig: &tls.Config{
-				InsecureSkipVerify: cc.Bool("github-ssl-insecure"), // skipcq: GSC-G402 false-positive
-				MinVersion:         tls.VersionTLS12,
-				MaxVersion:         tls.VersionTLS13,
-			},
+var ErrNotModified = errors.New("github api respond with 304, config has not been modified")
 
-			MaxConns: cc.Int("github-max-conns"),
+func NewHttpClient(cc *cli.Context, log *zerolog.Logger) (_ *HttpClient, e error) {
+	apibase := strings.TrimSuffix(cc.String("github-api-url"), "/")
+	if apibase == "" {
+		apibase = "https://" + cc.String("github-api-addr")
+	}
 
-			ReadTimeout:         cc.Duration("github-timeout-read"),
-			WriteTimeout:        cc.Duration("github-timeout-write"),
-			MaxIdleConnDuration: cc.Duration("github-timeout-idle"),
-			MaxConnDuration:     cc.Duration("github-timeout-conn"),
+	var furl *url.URL
+	var addr string
+	if furl, addr, e = parseBaseURL(apibase); e != nil {
+		return
+	}
 
-			DisableHeaderNamesNormalizing: false,
-			DisablePathNormalizing:        false,
-			NoDefaultUserAgentHeader:      false,
+	rri := fasthttp.AcquireURI()
+	apiurl := fmt.Sprintf("%s/repos/%s/contents/%s?ref=%s",
+		apibase,
+		cc.String("auth-github-repo"),
+		cc.String("auth-github-path"),
+		url.QueryEscape(cc.String("auth-github-branch")))
 
-			Dial: (&fasthttp.TCPDialer{
-				Concurrency:      cc.Int("github-tcpdial-concurr"),
-				DNSCacheDuration: cc.Duration("github-dnscache-dur"),
-			}).Dial,
+	if e = rri.Parse(nil, []byte(apiurl)); e != nil {
+		return
+	}
 
-			// !!!
-			// ? DialTimeout
-		},
+	var hclient *fasthttp.HostClient
+	if hclient, e = newHostClient(cc, log, addr, furl.Scheme == "https"); e != nil {
+		return
+	}
+
+	client := &HttpClient{
+		HostClient: hclient,
 
 		githuburi:    rri,
 		githubapiver: cc.String("github-api-version"),
+		githubrepo:   cc.String("auth-github-repo"),
+		githubpath:   cc.String("auth-github-path"),
+		githubbranch: cc.String("auth-github-branch"),
+
+		apibase: apibase,
+		apihost: furl.Host,
+
+		retry:   newRetryPolicy(cc),
+		maxsize: cc.Int("github-max-config-size"),
 
 		log: log,
 	}
+
+	if client.authorizer, e = newGithubAuthorizer(cc, client); e != nil {
+		return
+	}
+
+	if client.authorizer != nil {
+		log.Info().Msg("github api requests will be authenticated with " + client.authorizer.String())
+	} else {
+		log.Warn().Msg("github api requests are anonymous, private repositories are unavailable and limits are low")
+	}
+
+	return client, e
 }
 
+// FetchConfigFromGithub returns the config file or the directory listing
+// with GITHUB_TYPE_DIR type if auth-github-path is a directory
 func (m *HttpClient) FetchConfigFromGithub() (_ *GithubResponse, e error) {
 	req, rsp := m.acquireRequestResponse()
 	defer m.releaseRequestResponse(req, rsp)
 
-	if !m.ratereset.IsZero() && m.noRequestsInLimitWindow() {
-		return nil, errors.New("could not call github request because of limits, retry after " + m.ratereset.String())
-	}
-	defer m.updateGithubRateLimits(&rsp.Header)
-
-	if e = m.Do(req, rsp); e != nil {
-		return
-	}
+	e = m.doAuthorized(req, rsp)
 
 	if zerolog.GlobalLevel() <= zerolog.DebugLevel {
 		m.log.Trace().Msg(req.String())
 		m.log.Trace().Msg(rsp.String())
 	}
 
-	status, body := rsp.StatusCode(), rsp.Body()
-	if status < fasthttp.StatusOK || status >= fasthttp.StatusInternalServerError {
-		return nil, errors.New("github api respond with an unexpected status, is github down?")
-	} else if status >= fasthttp.StatusBadRequest && status < fasthttp.StatusInternalServerError {
-		return nil, errors.New("github api respond with a 4XX error, seems request builder had been failed")
+	if e != nil {
+		return
 	}
 
-	if utils.IsEmpty(body) {
+	body := rsp.Body()
+	if rsp.StatusCode() == fasthttp.StatusNotModified {
+		return nil, ErrNotModified
+	}
+
+	if body = bytes.TrimSpace(body); utils.IsEmpty(body) {
 		return nil, errors.New("github api respond with an empty body, unexpected result")
 	}
 
 	response := &GithubResponse{}
-	return response, easyjson.Unmarshal(rsp.Body(), response)
+	if body[0] == '[' {
+		response.Name, response.Path, response.Type = path.Base(m.githubpath), m.githubpath, GITHUB_TYPE_DIR
+		e = easyjson.Unmarshal(body, &response.Entries)
+	} else {
+		e = easyjson.Unmarshal(body, response)
+	}
+
+	if e != nil {
+		return
+	}
+
+	if response.Path == "" {
+		response.Path = m.githubpath
+	}
+
+	if e = m.completeContent(response); e != nil {
+		return
+	}
+
+	response.ETag = append([]byte(nil), rsp.Header.Peek(fasthttp.HeaderETag)...)
+	return response, e
+}
+
+// CommitETag saves the entity tag of the applied config; configs which are failed verification
+// must not be committed, otherwise they are hidden by 304 responses
+func (m *HttpClient) CommitETag(etag []byte) {
+	m.etag = append(m.etag[:0], etag...)
+}
+
+// FetchFileFromGithub returns the file of the config directory by its repository path
+func (m *HttpClient) FetchFileFromGithub(filepath string) (_ *GithubResponse, e error) {
+	req, rsp := m.acquireApiRequestResponse(fmt.Sprintf("/repos/%s/contents/%s?ref=%s",
+		m.githubrepo, filepath, url.QueryEscape(m.githubbranch)))
+	defer m.releaseRequestResponse(req, rsp)
+
+	if e = m.doAuthorized(req, rsp); e != nil {
+		return
+	}
+
+	response := &GithubResponse{}
+	if e = easyjson.Unmarshal(rsp.Body(), response); e != nil {
+		return
+	}
+
+	if e = m.completeContent(response); e != nil {
+		return
+	}
+
+	return response, validateContentsResponse(m.log, response)
 }
 
 func (m *HttpClient) ValidateGithubResponse(response *GithubResponse) error {
+	if response != nil && response.Type == GITHUB_TYPE_DIR {
+		m.log.Info().Msgf("downloaded directory listing %s with %d entries", response.Path, len(response.Entries))
+		return nil
+	}
+
+	return validateContentsResponse(m.log, response)
+}
+
+//
+//
+//
+
+func validateContentsResponse(log *zerolog.Logger, response *GithubResponse) error {
 	if response == nil {
 		return errors.New("BUG! given gihub response is nil")
 	}
 
 	if response.Message != "" || response.Status != 0 {
-		m.log.Trace().Msgf("status: %d; message: %s", response.Status, response.Message)
+		log.Trace().Msgf("status: %d; message: %s", response.Status, response.Message)
 		return errors.New("response has message/status field, seems github respond with an error")
 	}
 
 	if clen := len(response.Content); clen != response.Size {
-		m.log.Trace().Msgf("content len(): %d; reponse.size: %d", clen, response.Size)
+		log.Trace().Msgf("content len(): %d; reponse.size: %d", clen, response.Size)
 		return errors.New("response content length is not matches with responded size")
 	}
 
-	if response.Type != "file" {
-		m.log.Trace().Msgf("response type: %s; expecting 'file'", response.Type)
+	if response.Type != GITHUB_TYPE_FILE {
+		log.Trace().Msgf("response type: %s; expecting 'file'", response.Type)
 		return errors.New("unexpected response object type received")
 	}
 
-	m.log.Info().Msgf("downloaded and validated file %s with hash %s and length %d",
+	if hash := gitBlobHash(response.Content, response.Sha); hash != response.Sha {
+		log.Trace().Msgf("content hash: %s; response.sha: %s", hash, response.Sha)
+		return errors.New("response content hash is not matches with responded blob sha")
+	}
+
+	log.Info().Msgf("downloaded and validated file %s with hash %s and length %d",
 		response.Name, response.Sha, response.Size)
 	return nil
 }
 
-//
-//
-//
+// parseBaseURL checks the scheme and returns the dial address with the default port
+func parseBaseURL(rawurl string) (furl *url.URL, addr string, e error) {
+	if furl, e = url.Parse(rawurl); e != nil {
+		return
+	} else if furl.Scheme != "https" && furl.Scheme != "http" {
+		return nil, "", errors.New("unsupported scheme in url " + rawurl)
+	} else if furl.Host == "" {
+		return nil, "", errors.New("host is not defined in url " + rawurl)
+	}
 
-func (m *HttpClient) acquireRequestResponse() (req *fasthttp.Request, rsp *fasthttp.Response) {
-	req, rsp = fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	addr = furl.Host
+	if furl.Port() == "" {
+		addr = net.JoinHostPort(furl.Hostname(), map[string]string{"https": "443", "http": "80"}[furl.Scheme])
+	}
 
-	req.SetURI(m.githuburi)
+	return
+}
 
-	req.Header.Set(fasthttp.HeaderAccept, "application/vnd.github+json; charset=utf-8")
-	req.Header.Set(fasthttp.HeaderUserAgent, m.Name)
-	req.Header.Set(fasthttp.HeaderKeepAlive, "timeout=5, max=1000")
-	req.Header.Set(fasthttp.HeaderConnection, "keep-alive")
-	req.Header.Set(fasthttp.HeaderCacheControl, "no-cache")
-	req.Header.Set(fasthttp.HeaderPragma, "no-cache")
+// newTLSConfig loads custom CA bundle (system roots are kept) and the client certificate
+func newTLSConfig(cc *cli.Context) (_ *tls.Config, e error) {
+	config := &tls.Config{
+		InsecureSkipVerify: cc.Bool("github-ssl-insecure"), // skipcq: GSC-G402 false-positive
+		MinVersion:         tls.VersionTLS12,
+		MaxVersion:         tls.VersionTLS13,
+	}
 
-	req.Header.Set("X-GitHub-Api-Version", m.githubapiver)
+	if cc.String("github-ca-file") != "" {
+		if config.RootCAs, e = x509.SystemCertPool(); e != nil {
+			config.RootCAs = x509.NewCertPool()
+		}
 
-	req.Header.SetHost(strings.Split(m.Addr, ":")[0])
-	req.UseHostHeader = true
+		var bundle []byte
+		if bundle, e = os.ReadFile(cc.String("github-ca-file")); e != nil {
+			return
+		}
 
-	return
-}
+		if !config.RootCAs.AppendCertsFromPEM(bundle) {
+			return nil, errors.New("there are no certificates in github-ca-file " + cc.String("github-ca-file"))
+		}
+	}
 
-func (*HttpClient) releaseRequestResponse(req *fasthttp.Request, rsp *fasthttp.Response) {
-	fasthttp.ReleaseRequest(req)
-	fasthttp.ReleaseResponse(rsp)
+	if cc.String("github-client-cert") != "" || cc.String("github-client-key") != "" {
+		var certificate tls.Certificate
+		if certificate, e = tls.LoadX509KeyPair(cc.String("github-client-cert"), cc.String("github-client-key")); e != nil {
+			return nil, errors.New("could not load client certificate, " + e.Error())
+		}
+
+		config.Certificates = []tls.Certificate{certificate}
+	}
+
+	return config, e
 }
 
-func (m *HttpClient) noRequestsInLimitWindow() bool {
-	return m.rateremain <= 0 && time.Now().Before(m.ratereset)
+func newHostClient(cc *cli.Context, log *zerolog.Logger, addr string, istls bool) (_ *fasthttp.HostClient, e error) {
+	var tlsconfig *tls.Config
+	if tlsconfig, e = newTLSConfig(cc); e != nil {
+		return
+	}
+
+	var dial fasthttp.DialFunc
+	if dial, e = newDialFunc(cc, log, addr, istls); e != nil {
+		return
+	}
+
+	return &fasthttp.HostClient{
+		// https://developer.mozilla.org/en-US/docs/Web/HTTP/Headers/User-Agent#crawler_and_bot_ua_strings
+		Name: fmt.Sprintf("Mozilla/5.0 (compatible; %s/%s; +https://anilibria.top/support)",
+			cc.App.Name, cc.App.Version),
+
+		Addr:  addr,
+		IsTLS: istls,
+
+		TLSConfig: tlsconfig,
+
+		MaxConns: cc.Int("github-max-conns"),
+
+		ReadTimeout:         cc.Duration("github-timeout-read"),
+		WriteTimeout:        cc.Duration("github-timeout-write"),
+		MaxIdleConnDuration: cc.Duration("github-timeout-idle"),
+		MaxConnDuration:     cc.Duration("github-timeout-conn"),
+
+		DisableHeaderNamesNormalizing: false,
+		DisablePathNormalizing:        false,
+		NoDefaultUserAgentHeader:      false,
+
+		Dial: dial,
+
+		// !!!
+		// ? DialTimeout
+	}, e
 }
 
-func (m *HttpClient) updateGithubRateLimits(headers *fasthttp.ResponseHeader) (e error) {
-	var remaining, reset []byte
-	if remaining = headers.Peek("X-RateLimit-Remaining"); utils.IsEmpty(remaining) {
-		return errors.New("ratelimit headers (remaining) are empty")
+// doAuthorized performs the authorized request; 401 responses drop the cached github app
+// installation token (e.g. revoked one), the request is retried once with the new token
+func (m *HttpClient) doAuthorized(req *fasthttp.Request, rsp *fasthttp.Response) (e error) {
+	if e = m.authorize(req); e != nil {
+		return
+	}
+
+	var apierr *ApiError
+	if e = m.doWithRetry(req, rsp); !errors.As(e, &apierr) || apierr.Class != ERROR_UNAUTHORIZED {
+		return
 	}
 
-	if reset = headers.Peek("X-RateLimit-Reset"); utils.IsEmpty(reset) {
-		return errors.New("ratelimit headers (reset) are empty")
+	if m.authorizer == nil || !m.authorizer.invalidate() {
+		return
 	}
 
-	var remainingbuf int
-	if remainingbuf, e = strconv.Atoi(futils.UnsafeString(remaining)); e != nil {
+	m.log.Warn().Msg("github api respond with 401, retrying the request with the new " + m.authorizer.String() + " token")
+	if e = m.authorize(req); e != nil {
 		return
 	}
 
-	var resetbuf int64
-	if resetbuf, e = strconv.ParseInt(futils.UnsafeString(reset), 10, 64); e != nil {
+	return m.doWithRetry(req, rsp)
+}
+
+func (m *HttpClient) authorize(req *fasthttp.Request) (e error) {
+	if m.authorizer == nil {
 		return
 	}
 
-	m.rateremain = remainingbuf
-	m.ratereset = time.Unix(resetbuf, 0)
+	var authorization string
+	if authorization, e = m.authorizer.authorization(); e != nil {
+		return errors.New("could not authenticate github api request, " + e.Error())
+	}
+
+	req.Header.Set(fasthttp.HeaderAuthorization, authorization)
+	return
+}
+
+func (m *HttpClient) acquireRequestResponse() (req *fasthttp.Request, rsp *fasthttp.Response) {
+	req, rsp = m.acquireApiRequestResponse("")
+	req.SetURI(m.githuburi)
+
+	if !utils.IsEmpty(m.etag) {
+		req.Header.SetBytesV(fasthttp.HeaderIfNoneMatch, m.etag)
+	}
+
 	return
 }
+
+// acquireApiRequestResponse prepares request for the given api path, empty path is for the caller defined uri
+func (m *HttpClient) acquireApiRequestResponse(path string) (req *fasthttp.Request, rsp *fasthttp.Response) {
+	req, rsp = fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+
+	if path != "" {
+		req.SetRequestURI(m.apibase + path)
+	}
+
+	req.Header.Set(fasthttp.HeaderAccept, "application/vnd.github+json; charset=utf-8")
+	req.Header.Set(fasthttp.HeaderUserAgent, m.Name)
+	req.Header.Set(fasthttp.HeaderKeepAlive, "timeout=5, max=1000")
+	req.Header.Set(fasthttp.HeaderConnection, "keep-alive")
+	req.Header.Set(fasthttp.HeaderCacheControl, "no-cache")
+	req.Header.Set(fasthttp.HeaderPragma, "no-cache")
+
+	req.Header.Set("X-GitHub-Api-Version", m.githubapiver)
+
+	req.Header.SetHost(m.apihost)
+	req.UseHostHeader = true
+
+	return
+}
+
+func (*HttpClient) releaseRequestResponse(req *fasthttp.Request, rsp *fasthttp.Response) {
+	fasthttp.ReleaseRequest(req)
+	fasthttp.ReleaseResponse(rsp)
+}
@@ -38,6 +38,8 @@ func easyjson7e0ee8d0DecodeGithubComMindHunter86AsmasInternalGclient(in *jlexer.
 		switch key {
 		case "name":
 			out.Name = string(in.String())
+		case "path":
+			out.Path = string(in.String())
 		case "sha":
 			out.Sha = string(in.String())
 		case "size":
@@ -75,6 +77,16 @@ func easyjson7e0ee8d0EncodeGithubComMindHunter86AsmasInternalGclient(out *jwrite
 		out.RawString(prefix[1:])
 		out.String(string(in.Name))
 	}
+	if in.Path != "" {
+		const prefix string = ",\"path\":"
+		if first {
+			first = false
+			out.RawString(prefix[1:])
+		} else {
+			out.RawString(prefix)
+		}
+		out.String(string(in.Path))
+	}
 	if in.Sha != "" {
 		const prefix string = ",\"sha\":"
 		if first {
@@ -161,3 +173,81 @@ func (v *GithubResponse) UnmarshalJSON(data []byte) error {
 func (v *GithubResponse) UnmarshalEasyJSON(l *jlexer.Lexer) {
 	easyjson7e0ee8d0DecodeGithubComMindHunter86AsmasInternalGclient(l, v)
 }
+func easyjson7e0ee8d0DecodeGithubComMindHunter86AsmasInternalGclient1(in *jlexer.Lexer, out *GithubDirectory) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		in.Skip()
+		*out = nil
+	} else {
+		in.Delim('[')
+		if *out == nil {
+			if !in.IsDelim(']') {
+				*out = make(GithubDirectory, 0, 8)
+			} else {
+				*out = GithubDirectory{}
+			}
+		} else {
+			*out = (*out)[:0]
+		}
+		for !in.IsDelim(']') {
+			var v4 *GithubResponse
+			if in.IsNull() {
+				in.Skip()
+				v4 = nil
+			} else {
+				if v4 == nil {
+					v4 = new(GithubResponse)
+				}
+				(*v4).UnmarshalEasyJSON(in)
+			}
+			*out = append(*out, v4)
+			in.WantComma()
+		}
+		in.Delim(']')
+	}
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson7e0ee8d0EncodeGithubComMindHunter86AsmasInternalGclient1(out *jwriter.Writer, in GithubDirectory) {
+	if in == nil && (out.Flags&jwriter.NilSliceAsEmpty) == 0 {
+		out.RawString("null")
+	} else {
+		out.RawByte('[')
+		for v5, v6 := range in {
+			if v5 > 0 {
+				out.RawByte(',')
+			}
+			if v6 == nil {
+				out.RawString("null")
+			} else {
+				(*v6).MarshalEasyJSON(out)
+			}
+		}
+		out.RawByte(']')
+	}
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GithubDirectory) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson7e0ee8d0EncodeGithubComMindHunter86AsmasInternalGclient1(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GithubDirectory) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson7e0ee8d0EncodeGithubComMindHunter86AsmasInternalGclient1(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GithubDirectory) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson7e0ee8d0DecodeGithubComMindHunter86AsmasInternalGclient1(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GithubDirectory) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson7e0ee8d0DecodeGithubComMindHunter86AsmasInternalGclient1(l, v)
+}
@@ -0,0 +1,227 @@
+package gclient
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mailru/easyjson"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	// github allows app jwt lifetime up to 10 minutes
+	githubAppJWTLifetime = 9 * time.Minute
+	// installation tokens live 1 hour, they are refreshed before expiry
+	githubTokenRefreshBefore = 5 * time.Minute
+)
+
+type (
+	// githubAuthorizer returns the authorization header value for github api requests;
+	// invalidate drops cached credentials and reports whether the request may be retried
+	githubAuthorizer interface {
+		authorization() (string, error)
+		invalidate() bool
+		String() string
+	}
+
+	githubTokenAuth struct {
+		token string
+	}
+
+	githubAppAuth struct {
+		client *HttpClient
+
+		appid          int64
+		installationid int64
+		key            *rsa.PrivateKey
+
+		mu        sync.Mutex
+		token     string
+		expiresat time.Time
+	}
+
+	//easyjson:json
+	GithubInstallationToken struct {
+		Token     string    `json:"token"`
+		ExpiresAt time.Time `json:"expires_at"`
+	}
+)
+
+// newGithubAuthorizer returns nil authorizer for anonymous access if no credentials are defined
+func newGithubAuthorizer(cc *cli.Context, client *HttpClient) (_ githubAuthorizer, e error) {
+	if cc.Int64("github-app-id") != 0 {
+		return newGithubAppAuth(cc, client)
+	}
+
+	token := cc.String("github-token")
+	if cc.String("github-token-file") != "" {
+		var payload []byte
+		if payload, e = os.ReadFile(cc.String("github-token-file")); e != nil {
+			return
+		}
+
+		token = strings.TrimSpace(string(payload))
+	}
+
+	if token == "" {
+		return
+	}
+
+	return &githubTokenAuth{token: token}, e
+}
+
+func (m *githubTokenAuth) authorization() (string, error) {
+	return "Bearer " + m.token, nil
+}
+
+func (*githubTokenAuth) invalidate() bool { return false }
+
+func (*githubTokenAuth) String() string { return "personal access token" }
+
+func newGithubAppAuth(cc *cli.Context, client *HttpClient) (_ *githubAppAuth, e error) {
+	auth := &githubAppAuth{
+		client:         client,
+		appid:          cc.Int64("github-app-id"),
+		installationid: cc.Int64("github-app-installation-id"),
+	}
+
+	if auth.installationid == 0 {
+		return nil, errors.New("github app authentication requires github-app-installation-id flag")
+	}
+
+	var payload []byte
+	if payload, e = os.ReadFile(cc.String("github-app-private-key-file")); e != nil {
+		return nil, errors.New("could not read github app private key, " + e.Error())
+	}
+
+	if auth.key, e = parseRSAPrivateKey(payload); e != nil {
+		return nil, errors.New("could not parse github app private key, " + e.Error())
+	}
+
+	return auth, e
+}
+
+func (m *githubAppAuth) String() string {
+	return fmt.Sprintf("github app %d installation %d", m.appid, m.installationid)
+}
+
+// authorization returns the cached installation token, it's refreshed before expiry
+func (m *githubAppAuth) authorization() (_ string, e error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.token != "" && time.Until(m.expiresat) > githubTokenRefreshBefore {
+		return "Bearer " + m.token, e
+	}
+
+	var token *GithubInstallationToken
+	if token, e = m.requestInstallationToken(); e != nil {
+		return
+	}
+
+	m.token, m.expiresat = token.Token, token.ExpiresAt
+	m.client.log.Info().Msgf("github app installation token has been refreshed, it expires at %s",
+		m.expiresat.Format(time.RFC3339))
+
+	return "Bearer " + m.token, e
+}
+
+// invalidate drops the cached installation token, it's requested again by the next authorization
+func (m *githubAppAuth) invalidate() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.token, m.expiresat = "", time.Time{}
+	return true
+}
+
+//
+//
+//
+
+func (m *githubAppAuth) requestInstallationToken() (_ *GithubInstallationToken, e error) {
+	var jwt string
+	if jwt, e = m.signJWT(time.Now()); e != nil {
+		return
+	}
+
+	req, rsp := m.client.acquireApiRequestResponse(
+		fmt.Sprintf("/app/installations/%d/access_tokens", m.installationid))
+	defer m.client.releaseRequestResponse(req, rsp)
+
+	req.Header.SetMethod(fasthttp.MethodPost)
+	req.Header.Set(fasthttp.HeaderAuthorization, "Bearer "+jwt)
+
+	if e = m.client.doWithRetry(req, rsp); e != nil {
+		return
+	}
+
+	if zerolog.GlobalLevel() <= zerolog.DebugLevel {
+		m.client.log.Trace().Msg(rsp.String())
+	}
+
+	token := &GithubInstallationToken{}
+	if e = easyjson.Unmarshal(rsp.Body(), token); e != nil {
+		return nil, fmt.Errorf("could not decode installation token response with status %d, %s", rsp.StatusCode(), e.Error())
+	}
+
+	if token.Token == "" || token.ExpiresAt.IsZero() {
+		return nil, errors.New("github api respond with an empty installation token")
+	}
+
+	return token, e
+}
+
+// signJWT returns RS256 jwt of the app; iat is backdated for clock drift
+func (m *githubAppAuth) signJWT(now time.Time) (_ string, e error) {
+	header := base64.RawURLEncoding.EncodeToString([]byte(`{"alg":"RS256","typ":"JWT"}`))
+	claims := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf(`{"iat":%d,"exp":%d,"iss":"%s"}`,
+		now.Add(-time.Minute).Unix(), now.Add(githubAppJWTLifetime).Unix(), strconv.FormatInt(m.appid, 10))))
+
+	digest := sha256.Sum256([]byte(header + "." + claims))
+
+	var sign []byte
+	if sign, e = rsa.SignPKCS1v15(rand.Reader, m.key, crypto.SHA256, digest[:]); e != nil {
+		return
+	}
+
+	return header + "." + claims + "." + base64.RawURLEncoding.EncodeToString(sign), e
+}
+
+// parseRSAPrivateKey accepts PKCS #1 keys generated by github and PKCS #8 keys
+func parseRSAPrivateKey(payload []byte) (_ *rsa.PrivateKey, e error) {
+	var block *pem.Block
+	if block, _ = pem.Decode(payload); block == nil {
+		return nil, errors.New("private key pem not found")
+	}
+
+	if block.Type == "RSA PRIVATE KEY" {
+		return x509.ParsePKCS1PrivateKey(block.Bytes)
+	}
+
+	var key interface{}
+	if key, e = x509.ParsePKCS8PrivateKey(block.Bytes); e != nil {
+		return
+	}
+
+	rsakey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T, rsa is expected", key)
+	}
+
+	return rsakey, e
+}
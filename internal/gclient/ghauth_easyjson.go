@@ -0,0 +1,94 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package gclient
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonB532c04fDecodeGithubComMindHunter86AsmasInternalGclient(in *jlexer.Lexer, out *GithubInstallationToken) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "token":
+			out.Token = string(in.String())
+		case "expires_at":
+			if data := in.Raw(); in.Ok() {
+				in.AddError((out.ExpiresAt).UnmarshalJSON(data))
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonB532c04fEncodeGithubComMindHunter86AsmasInternalGclient(out *jwriter.Writer, in GithubInstallationToken) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"token\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Token))
+	}
+	{
+		const prefix string = ",\"expires_at\":"
+		out.RawString(prefix)
+		out.Raw((in.ExpiresAt).MarshalJSON())
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GithubInstallationToken) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonB532c04fEncodeGithubComMindHunter86AsmasInternalGclient(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GithubInstallationToken) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonB532c04fEncodeGithubComMindHunter86AsmasInternalGclient(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GithubInstallationToken) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonB532c04fDecodeGithubComMindHunter86AsmasInternalGclient(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GithubInstallationToken) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonB532c04fDecodeGithubComMindHunter86AsmasInternalGclient(l, v)
+}
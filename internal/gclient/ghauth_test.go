@@ -0,0 +1,265 @@
+package gclient
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/valyala/fasthttp"
+)
+
+const (
+	testAppID          = 7
+	testInstallationID = 42
+	testConfigContent  = "config:\n  authorization_list: []\n"
+)
+
+// githubStandIn is the local github api stand-in which issues installation tokens
+// for valid app jwts and serves the config for the last issued token only
+type githubStandIn struct {
+	t   *testing.T
+	key *rsa.PublicKey
+
+	mu        sync.Mutex
+	exchanges int
+	requests  int
+	token     string
+	ttl       time.Duration
+}
+
+func (m *githubStandIn) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	switch {
+	case r.Method == http.MethodPost && r.URL.Path == fmt.Sprintf("/app/installations/%d/access_tokens", testInstallationID):
+		if e := m.verifyJWT(strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")); e != nil {
+			m.t.Errorf("installation token is requested with invalid jwt, %s", e.Error())
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"A JSON web token could not be decoded"}`)
+			return
+		}
+
+		m.exchanges++
+		m.token = fmt.Sprintf("ghs_token%d", m.exchanges)
+
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"token":"%s","expires_at":"%s"}`, m.token, time.Now().Add(m.ttl).UTC().Format(time.RFC3339))
+	case r.Method == http.MethodGet && r.URL.Path == "/repos/owner/repo/contents/config.yaml":
+		m.requests++
+
+		if m.token == "" || r.Header.Get("Authorization") != "Bearer "+m.token {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"message":"Bad credentials"}`)
+			return
+		}
+
+		fmt.Fprintf(w, `{"name":"config.yaml","path":"config.yaml","type":"file","size":%d,"content":"%s"}`,
+			len(testConfigContent), base64.StdEncoding.EncodeToString([]byte(testConfigContent)))
+	default:
+		m.t.Errorf("unexpected github api request %s %s", r.Method, r.URL.Path)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (m *githubStandIn) verifyJWT(jwt string) (e error) {
+	parts := strings.Split(jwt, ".")
+	if len(parts) != 3 {
+		return fmt.Errorf("jwt has %d parts", len(parts))
+	}
+
+	var sign []byte
+	if sign, e = base64.RawURLEncoding.DecodeString(parts[2]); e != nil {
+		return
+	}
+
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if e = rsa.VerifyPKCS1v15(m.key, crypto.SHA256, digest[:], sign); e != nil {
+		return
+	}
+
+	var payload []byte
+	if payload, e = base64.RawURLEncoding.DecodeString(parts[1]); e != nil {
+		return
+	}
+
+	var claims struct {
+		Iat int64  `json:"iat"`
+		Exp int64  `json:"exp"`
+		Iss string `json:"iss"`
+	}
+	if e = json.Unmarshal(payload, &claims); e != nil {
+		return
+	}
+
+	now := time.Now().Unix()
+	switch {
+	case claims.Iss != fmt.Sprint(testAppID):
+		return fmt.Errorf("unexpected iss %s", claims.Iss)
+	case claims.Iat > now || claims.Exp <= now:
+		return fmt.Errorf("jwt is not valid now, iat %d exp %d", claims.Iat, claims.Exp)
+	case claims.Exp-claims.Iat > int64((10 * time.Minute).Seconds()):
+		return fmt.Errorf("jwt lifetime %ds exceeds 10 minutes", claims.Exp-claims.Iat)
+	}
+
+	return
+}
+
+func (m *githubStandIn) counters() (exchanges, requests int) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return m.exchanges, m.requests
+}
+
+func (m *githubStandIn) revoke() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.token = "revoked"
+}
+
+func newTestAppClient(t *testing.T) (*HttpClient, *githubAppAuth, *githubStandIn) {
+	t.Helper()
+
+	key, e := rsa.GenerateKey(rand.Reader, 2048)
+	if e != nil {
+		t.Fatal(e)
+	}
+
+	standin := &githubStandIn{t: t, key: &key.PublicKey, ttl: time.Hour}
+	server := httptest.NewServer(standin)
+	t.Cleanup(server.Close)
+
+	log := zerolog.Nop()
+	apibase := server.URL
+
+	uri := fasthttp.AcquireURI()
+	if e = uri.Parse(nil, []byte(apibase+"/repos/owner/repo/contents/config.yaml?ref=master")); e != nil {
+		t.Fatal(e)
+	}
+
+	client := &HttpClient{
+		HostClient: &fasthttp.HostClient{Name: "asmas-test", Addr: strings.TrimPrefix(apibase, "http://")},
+
+		githuburi:    uri,
+		githubrepo:   "owner/repo",
+		githubpath:   "config.yaml",
+		githubbranch: "master",
+
+		apibase: apibase,
+		apihost: strings.TrimPrefix(apibase, "http://"),
+
+		retry:   &retryPolicy{attempts: 1},
+		maxsize: 1024 * 1024,
+
+		log: &log,
+	}
+
+	auth := &githubAppAuth{client: client, appid: testAppID, installationid: testInstallationID, key: key}
+	client.authorizer = auth
+
+	return client, auth, standin
+}
+
+func fetchTestConfig(t *testing.T, client *HttpClient) {
+	t.Helper()
+
+	response, e := client.FetchConfigFromGithub()
+	if e != nil {
+		t.Fatalf("could not fetch config, %s", e.Error())
+	}
+
+	if string(response.Content) != testConfigContent {
+		t.Fatalf("unexpected config content %q", response.Content)
+	}
+}
+
+func TestGithubAppTokenExchange(t *testing.T) {
+	client, auth, standin := newTestAppClient(t)
+
+	fetchTestConfig(t, client)
+	if exchanges, requests := standin.counters(); exchanges != 1 || requests != 1 {
+		t.Fatalf("expected 1 token exchange and 1 request, got %d and %d", exchanges, requests)
+	}
+
+	if auth.token != "ghs_token1" || time.Until(auth.expiresat) < 50*time.Minute {
+		t.Fatalf("unexpected cached installation token %s expiring at %s", auth.token, auth.expiresat)
+	}
+}
+
+func TestGithubAppTokenCaching(t *testing.T) {
+	client, _, standin := newTestAppClient(t)
+
+	for i := 0; i < 3; i++ {
+		fetchTestConfig(t, client)
+	}
+
+	if exchanges, requests := standin.counters(); exchanges != 1 || requests != 3 {
+		t.Fatalf("expected 1 token exchange and 3 requests, got %d and %d", exchanges, requests)
+	}
+}
+
+func TestGithubAppTokenRefresh(t *testing.T) {
+	client, auth, standin := newTestAppClient(t)
+
+	fetchTestConfig(t, client)
+
+	// the token expiring within githubTokenRefreshBefore is refreshed before the request
+	auth.expiresat = time.Now().Add(githubTokenRefreshBefore - time.Minute)
+	fetchTestConfig(t, client)
+
+	if exchanges, requests := standin.counters(); exchanges != 2 || requests != 2 {
+		t.Fatalf("expected 2 token exchanges and 2 requests, got %d and %d", exchanges, requests)
+	}
+
+	if auth.token != "ghs_token2" {
+		t.Fatalf("expected refreshed token ghs_token2, got %s", auth.token)
+	}
+}
+
+func TestGithubAppUnauthorizedRetry(t *testing.T) {
+	client, auth, standin := newTestAppClient(t)
+
+	fetchTestConfig(t, client)
+
+	// the cached token is revoked by github, 401 response must drop it and retry the request
+	standin.revoke()
+	fetchTestConfig(t, client)
+
+	if exchanges, requests := standin.counters(); exchanges != 2 || requests != 3 {
+		t.Fatalf("expected 2 token exchanges and 3 requests, got %d and %d", exchanges, requests)
+	}
+
+	if auth.token != "ghs_token2" {
+		t.Fatalf("expected new token ghs_token2 after 401, got %s", auth.token)
+	}
+}
+
+func TestGithubTokenUnauthorizedIsNotRetried(t *testing.T) {
+	client, _, standin := newTestAppClient(t)
+	client.authorizer = &githubTokenAuth{token: "github_pat_invalid"}
+
+	_, e := client.FetchConfigFromGithub()
+
+	var apierr *ApiError
+	if !errors.As(e, &apierr) || apierr.Class != ERROR_UNAUTHORIZED {
+		t.Fatalf("expected unauthorized api error, got %v", e)
+	}
+
+	if exchanges, requests := standin.counters(); exchanges != 0 || requests != 1 {
+		t.Fatalf("expected no token exchanges and 1 request, got %d and %d", exchanges, requests)
+	}
+}
@@ -0,0 +1,97 @@
+package gclient
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasthttp/fasthttpproxy"
+	"golang.org/x/net/http/httpproxy"
+)
+
+// newDialFunc returns the dial function of the api host client; connections are made through
+// the http connect or socks5 proxy if it's configured for the api address and not excluded by no-proxy
+func newDialFunc(cc *cli.Context, log *zerolog.Logger, addr string, istls bool) (_ fasthttp.DialFunc, e error) {
+	dialer := &fasthttpproxy.Dialer{
+		TCPDialer: fasthttp.TCPDialer{
+			Concurrency:      cc.Int("github-tcpdial-concurr"),
+			DNSCacheDuration: cc.Duration("github-dnscache-dur"),
+		},
+
+		// CONNECT request must not hang forever on the broken proxy
+		ConnectTimeout: cc.Duration("github-timeout-read"),
+	}
+
+	var proxyurl *url.URL
+	if proxyurl, e = resolveProxyURL(cc, addr, istls); e != nil {
+		return
+	}
+
+	if proxyurl == nil {
+		log.Debug().Msg("config source api " + addr + " will be dialed directly")
+		return dialer.TCPDialer.Dial, e
+	}
+
+	// the proxy is resolved once for the only address of the host client
+	dialer.Config = httpproxy.Config{
+		HTTPProxy:  proxyurl.String(),
+		HTTPSProxy: proxyurl.String(),
+	}
+
+	log.Info().Msgf("config source api %s will be dialed through %s proxy %s", addr, proxyurl.Scheme, proxyurl.Redacted())
+	return dialer.GetDialFunc(false)
+}
+
+//
+//
+//
+
+// resolveProxyURL returns the proxy of github-proxy flag or HTTPS_PROXY/HTTP_PROXY environment;
+// nil is returned for direct connections
+func resolveProxyURL(cc *cli.Context, addr string, istls bool) (_ *url.URL, e error) {
+	config := &httpproxy.Config{NoProxy: cc.String("github-no-proxy")}
+
+	if proxy := cc.String("github-proxy"); proxy != "" {
+		config.HTTPProxy, config.HTTPSProxy = proxy, proxy
+	} else if cc.Bool("github-proxy-from-env") {
+		environment := httpproxy.FromEnvironment()
+		config.HTTPProxy, config.HTTPSProxy = environment.HTTPProxy, environment.HTTPSProxy
+	}
+
+	if config.HTTPProxy == "" && config.HTTPSProxy == "" {
+		return
+	}
+
+	target := &url.URL{Scheme: "http", Host: addr}
+	if istls {
+		target.Scheme = "https"
+	}
+
+	var proxyurl *url.URL
+	if proxyurl, e = config.ProxyFunc()(target); e != nil {
+		return nil, errors.New("could not parse proxy url, " + e.Error())
+	} else if proxyurl == nil {
+		return
+	}
+
+	switch proxyurl.Scheme {
+	case "http", "socks5", "socks5h":
+	default:
+		return nil, errors.New("unsupported proxy scheme " + proxyurl.Scheme + ", only http and socks5 proxies are supported")
+	}
+
+	// credentials of the flag override ones given in the proxy url
+	if auth := cc.String("github-proxy-auth"); auth != "" {
+		username, password, ok := strings.Cut(auth, ":")
+		if !ok {
+			return nil, errors.New("github-proxy-auth must be given in user:password format")
+		}
+
+		proxyurl.User = url.UserPassword(username, password)
+	}
+
+	return proxyurl, e
+}
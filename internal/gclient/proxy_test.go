@@ -0,0 +1,292 @@
+package gclient
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/binary"
+	"flag"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+	"github.com/valyala/fasthttp"
+)
+
+// the api address is never resolved by the client, the proxy stand-ins tunnel it to the backend;
+// loopback addresses can't be used here, they are never proxied by httpproxy
+const testProxiedAddr = "api.github.test:80"
+
+// proxyStandIn is the local http connect or socks5 proxy which records tunnel
+// targets and credentials and tunnels all connections to the backend
+type proxyStandIn struct {
+	t       *testing.T
+	backend string
+
+	mu          sync.Mutex
+	targets     []string
+	credentials []string
+}
+
+func newProxyStandIn(t *testing.T, backend string, serve func(*proxyStandIn, net.Conn)) (*proxyStandIn, string) {
+	t.Helper()
+
+	listener, e := net.Listen("tcp", "127.0.0.1:0")
+	if e != nil {
+		t.Fatal(e)
+	}
+	t.Cleanup(func() { listener.Close() })
+
+	proxy := &proxyStandIn{t: t, backend: backend}
+	go func() {
+		for {
+			conn, e := listener.Accept()
+			if e != nil {
+				return
+			}
+
+			go serve(proxy, conn)
+		}
+	}()
+
+	return proxy, listener.Addr().String()
+}
+
+func (m *proxyStandIn) record(target, credentials string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.targets, m.credentials = append(m.targets, target), append(m.credentials, credentials)
+}
+
+func (m *proxyStandIn) recorded() (targets, credentials []string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	return append([]string(nil), m.targets...), append([]string(nil), m.credentials...)
+}
+
+func (m *proxyStandIn) tunnel(conn net.Conn, client io.Reader) {
+	backend, e := net.Dial("tcp", m.backend)
+	if e != nil {
+		m.t.Errorf("proxy stand-in could not dial the backend, %s", e.Error())
+		return
+	}
+	defer backend.Close()
+
+	go io.Copy(backend, client) // nolint:errcheck
+	io.Copy(conn, backend)      // nolint:errcheck
+}
+
+func serveConnect(m *proxyStandIn, conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	req, e := http.ReadRequest(reader)
+	if e != nil {
+		return
+	}
+
+	if req.Method != http.MethodConnect {
+		m.t.Errorf("unexpected proxy request method %s", req.Method)
+		return
+	}
+
+	var credentials string
+	if auth := req.Header.Get("Proxy-Authorization"); strings.HasPrefix(auth, "Basic ") {
+		decoded, _ := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+		credentials = string(decoded)
+	}
+	m.record(req.Host, credentials)
+
+	fmt.Fprint(conn, "HTTP/1.1 200 Connection established\r\n\r\n")
+	m.tunnel(conn, reader)
+}
+
+// serveSocks5 implements no-auth and username/password methods and connect command only
+func serveSocks5(m *proxyStandIn, conn net.Conn) {
+	defer conn.Close()
+
+	reader := bufio.NewReader(conn)
+	read := func(n int) []byte {
+		buf := make([]byte, n)
+		if _, e := io.ReadFull(reader, buf); e != nil {
+			panic(e)
+		}
+		return buf
+	}
+	defer func() {
+		if r := recover(); r != nil && r != io.EOF {
+			m.t.Errorf("socks5 stand-in could not read the request, %v", r)
+		}
+	}()
+
+	greeting := read(2)
+	methods := read(int(greeting[1]))
+
+	var credentials string
+	if strings.IndexByte(string(methods), 0x02) != -1 {
+		conn.Write([]byte{0x05, 0x02}) // nolint:errcheck
+
+		version := read(2)
+		username := string(read(int(version[1])))
+		password := string(read(int(read(1)[0])))
+		credentials = username + ":" + password
+
+		conn.Write([]byte{0x01, 0x00}) // nolint:errcheck
+	} else {
+		conn.Write([]byte{0x05, 0x00}) // nolint:errcheck
+	}
+
+	header := read(4)
+	var host string
+	switch header[3] {
+	case 0x01:
+		host = net.IP(read(4)).String()
+	case 0x03:
+		host = string(read(int(read(1)[0])))
+	case 0x04:
+		host = net.IP(read(16)).String()
+	}
+	port := binary.BigEndian.Uint16(read(2))
+	m.record(net.JoinHostPort(host, strconv.Itoa(int(port))), credentials)
+
+	conn.Write([]byte{0x05, 0x00, 0x00, 0x01, 0, 0, 0, 0, 0, 0}) // nolint:errcheck
+	m.tunnel(conn, reader)
+}
+
+func newTestProxyContext(t *testing.T, values map[string]string) *cli.Context {
+	t.Helper()
+
+	set := flag.NewFlagSet("test", flag.ContinueOnError)
+	set.Int("github-tcpdial-concurr", 0, "")
+	set.Duration("github-dnscache-dur", time.Minute, "")
+	set.Duration("github-timeout-read", 5*time.Second, "")
+	set.String("github-proxy", "", "")
+	set.String("github-proxy-auth", "", "")
+	set.String("github-no-proxy", "", "")
+	set.Bool("github-proxy-from-env", false, "")
+
+	for name, value := range values {
+		if e := set.Set(name, value); e != nil {
+			t.Fatal(e)
+		}
+	}
+
+	return cli.NewContext(cli.NewApp(), set, nil)
+}
+
+func newTestProxyBackend(t *testing.T) string {
+	t.Helper()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, "proxied "+r.Host)
+	}))
+	t.Cleanup(server.Close)
+
+	return strings.TrimPrefix(server.URL, "http://")
+}
+
+func fetchThroughDial(t *testing.T, cc *cli.Context) (_ string, e error) {
+	t.Helper()
+
+	log := zerolog.Nop()
+
+	var dial fasthttp.DialFunc
+	if dial, e = newDialFunc(cc, &log, testProxiedAddr, false); e != nil {
+		t.Fatalf("could not create dial function, %s", e.Error())
+	}
+
+	client := &fasthttp.HostClient{Addr: testProxiedAddr, Dial: dial}
+
+	req, rsp := fasthttp.AcquireRequest(), fasthttp.AcquireResponse()
+	defer fasthttp.ReleaseRequest(req)
+	defer fasthttp.ReleaseResponse(rsp)
+
+	req.SetRequestURI("http://" + testProxiedAddr + "/")
+	if e = client.DoTimeout(req, rsp, 5*time.Second); e != nil {
+		return
+	}
+
+	return string(rsp.Body()), e
+}
+
+func TestDialThroughConnectProxy(t *testing.T) {
+	proxy, addr := newProxyStandIn(t, newTestProxyBackend(t), serveConnect)
+
+	body, e := fetchThroughDial(t, newTestProxyContext(t, map[string]string{
+		"github-proxy":      "http://" + addr,
+		"github-proxy-auth": "user:secret",
+	}))
+	if e != nil {
+		t.Fatalf("could not fetch through connect proxy, %s", e.Error())
+	}
+
+	if body != "proxied "+testProxiedAddr {
+		t.Fatalf("unexpected response body %q", body)
+	}
+
+	targets, credentials := proxy.recorded()
+	if len(targets) != 1 || targets[0] != testProxiedAddr {
+		t.Fatalf("expected one tunnel to %s, got %v", testProxiedAddr, targets)
+	}
+
+	if credentials[0] != "user:secret" {
+		t.Fatalf("expected proxy credentials user:secret, got %q", credentials[0])
+	}
+}
+
+func TestDialThroughSocks5Proxy(t *testing.T) {
+	proxy, addr := newProxyStandIn(t, newTestProxyBackend(t), serveSocks5)
+
+	body, e := fetchThroughDial(t, newTestProxyContext(t, map[string]string{
+		"github-proxy": "socks5://user:secret@" + addr,
+	}))
+	if e != nil {
+		t.Fatalf("could not fetch through socks5 proxy, %s", e.Error())
+	}
+
+	if body != "proxied "+testProxiedAddr {
+		t.Fatalf("unexpected response body %q", body)
+	}
+
+	targets, credentials := proxy.recorded()
+	if len(targets) != 1 || targets[0] != testProxiedAddr {
+		t.Fatalf("expected one tunnel to %s, got %v", testProxiedAddr, targets)
+	}
+
+	if credentials[0] != "user:secret" {
+		t.Fatalf("expected proxy credentials user:secret, got %q", credentials[0])
+	}
+}
+
+func TestNoProxyBypassesProxy(t *testing.T) {
+	proxy, addr := newProxyStandIn(t, newTestProxyBackend(t), serveConnect)
+
+	cc := newTestProxyContext(t, map[string]string{
+		"github-proxy":    "http://" + addr,
+		"github-no-proxy": ".github.test",
+	})
+
+	proxyurl, e := resolveProxyURL(cc, testProxiedAddr, false)
+	if e != nil || proxyurl != nil {
+		t.Fatalf("expected direct connection for no-proxy address, got %v, %v", proxyurl, e)
+	}
+
+	// the direct dial can't resolve the test address, it just must not reach the proxy
+	if _, e = fetchThroughDial(t, cc); e == nil {
+		t.Fatal("expected direct dial error for the unresolvable test address")
+	}
+
+	if targets, _ := proxy.recorded(); len(targets) != 0 {
+		t.Fatalf("expected no proxy tunnels for no-proxy address, got %v", targets)
+	}
+}
@@ -0,0 +1,82 @@
+package gclient
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/MindHunter86/asmas/internal/metrics"
+	futils "github.com/gofiber/fiber/v2/utils"
+	"github.com/valyala/fasthttp"
+)
+
+var (
+	metricRateRemaining = metrics.NewGauge("asmas_github_ratelimit_remaining",
+		"github api requests remaining in the current rate limit window", "resource")
+	metricRateLimit = metrics.NewGauge("asmas_github_ratelimit_limit",
+		"github api requests allowed in the rate limit window", "resource")
+	metricRateReset = metrics.NewGauge("asmas_github_ratelimit_reset_timestamp_seconds",
+		"unix time of the github api rate limit window reset", "resource")
+)
+
+// rateState is github rate limits received in the last response headers;
+// blockeduntil is set by rate limited responses and Retry-After
+type rateState struct {
+	mu           sync.Mutex
+	remaining    int
+	reset        time.Time
+	blockeduntil time.Time
+}
+
+// wait returns the duration before the next allowed request
+func (m *rateState) wait(now time.Time) (wait time.Duration) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.blockeduntil.After(now) {
+		wait = m.blockeduntil.Sub(now)
+	}
+
+	if !m.reset.IsZero() && m.remaining <= 0 && m.reset.After(now) && m.reset.Sub(now) > wait {
+		wait = m.reset.Sub(now)
+	}
+
+	return
+}
+
+func (m *rateState) block(until time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if until.After(m.blockeduntil) {
+		m.blockeduntil = until
+	}
+}
+
+// update parses X-RateLimit-* headers; responses without them (i.e. from proxies) are ignored
+func (m *rateState) update(headers *fasthttp.ResponseHeader) {
+	remaining, e := strconv.Atoi(futils.UnsafeString(headers.Peek("X-RateLimit-Remaining")))
+	if e != nil {
+		return
+	}
+
+	reset, e := strconv.ParseInt(futils.UnsafeString(headers.Peek("X-RateLimit-Reset")), 10, 64)
+	if e != nil {
+		return
+	}
+
+	resource := string(headers.Peek("X-RateLimit-Resource"))
+	if resource == "" {
+		resource = "core"
+	}
+
+	m.mu.Lock()
+	m.remaining, m.reset = remaining, time.Unix(reset, 0)
+	m.mu.Unlock()
+
+	metricRateRemaining.Set(float64(remaining), resource)
+	metricRateReset.Set(float64(reset), resource)
+	if limit, e := strconv.Atoi(futils.UnsafeString(headers.Peek("X-RateLimit-Limit"))); e == nil {
+		metricRateLimit.Set(float64(limit), resource)
+	}
+}
@@ -0,0 +1,250 @@
+package gclient
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/MindHunter86/asmas/internal/metrics"
+	futils "github.com/gofiber/fiber/v2/utils"
+	"github.com/mailru/easyjson"
+	"github.com/urfave/cli/v2"
+	"github.com/valyala/fasthttp"
+)
+
+type ErrorClass uint8
+
+const (
+	ERROR_TRANSIENT ErrorClass = iota
+	ERROR_RATE_LIMITED
+	ERROR_SECONDARY_RATE_LIMITED
+	ERROR_UNAUTHORIZED
+	ERROR_FORBIDDEN
+	ERROR_NOT_FOUND
+	ERROR_CLIENT
+)
+
+func (m ErrorClass) String() string {
+	switch m {
+	case ERROR_TRANSIENT:
+		return "transient"
+	case ERROR_RATE_LIMITED:
+		return "rate_limited"
+	case ERROR_SECONDARY_RATE_LIMITED:
+		return "secondary_rate_limited"
+	case ERROR_UNAUTHORIZED:
+		return "unauthorized"
+	case ERROR_FORBIDDEN:
+		return "forbidden"
+	case ERROR_NOT_FOUND:
+		return "not_found"
+	case ERROR_CLIENT:
+		return "client_error"
+	default:
+		return "undefined"
+	}
+}
+
+// github asks to wait at least one minute after secondary rate limit responses without Retry-After
+const secondaryRateLimitWait = time.Minute
+
+var (
+	metricRequests = metrics.NewCounter("asmas_github_requests_total",
+		"github api requests by result (ok or error class)", "result")
+	metricRetries = metrics.NewCounter("asmas_github_retries_total",
+		"github api request retries by error class", "class")
+)
+
+type (
+	// ApiError is the classified github api error response or the network error
+	ApiError struct {
+		Class      ErrorClass
+		Status     int
+		Message    string
+		RetryAfter time.Duration
+	}
+
+	//easyjson:json
+	GithubError struct {
+		Message string `json:"message"`
+	}
+
+	retryPolicy struct {
+		attempts   int
+		backoff    time.Duration
+		maxbackoff time.Duration
+	}
+)
+
+func (m *ApiError) Error() string {
+	if m.Status == 0 {
+		return fmt.Sprintf("github api request failed (%s), %s", m.Class, m.Message)
+	}
+
+	return fmt.Sprintf("github api respond with %d (%s), %s", m.Status, m.Class, m.Message)
+}
+
+// Temporary reports whether the request may succeed later without configuration changes
+func (m *ApiError) Temporary() bool {
+	switch m.Class {
+	case ERROR_TRANSIENT, ERROR_RATE_LIMITED, ERROR_SECONDARY_RATE_LIMITED:
+		return true
+	default:
+		return false
+	}
+}
+
+// RetryAfter returns the delay requested by github for the given error, if any
+func RetryAfter(e error) time.Duration {
+	var apierr *ApiError
+	if errors.As(e, &apierr) {
+		return apierr.RetryAfter
+	}
+
+	return 0
+}
+
+func newRetryPolicy(cc *cli.Context) *retryPolicy {
+	return &retryPolicy{
+		attempts:   cc.Int("github-retry-attempts"),
+		backoff:    cc.Duration("github-retry-backoff"),
+		maxbackoff: cc.Duration("github-retry-max-backoff"),
+	}
+}
+
+// delay returns exponential backoff with full jitter for the given retry number
+func (m *retryPolicy) delay(retry int) time.Duration {
+	backoff := m.backoff << retry
+	if backoff <= 0 || backoff > m.maxbackoff {
+		backoff = m.maxbackoff
+	}
+
+	if backoff <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int63n(int64(backoff))) // skipcq: GSC-G404 jitter only
+}
+
+//
+//
+//
+
+// doWithRetry performs the request and retries temporary errors; 2xx and 304 responses
+// are returned as is, other responses and network errors are returned as *ApiError
+func (m *HttpClient) doWithRetry(req *fasthttp.Request, rsp *fasthttp.Response) (e error) {
+	for retry := 0; ; retry++ {
+		if wait := m.rate.wait(time.Now()); wait > 0 {
+			return &ApiError{Class: ERROR_RATE_LIMITED, RetryAfter: wait,
+				Message: "requests are suspended because of limits, retry after " + wait.Round(time.Second).String()}
+		}
+
+		apierr := m.do(req, rsp)
+		if apierr == nil {
+			metricRequests.Inc("ok")
+			return
+		}
+		metricRequests.Inc(apierr.Class.String())
+
+		if apierr.RetryAfter > 0 {
+			m.rate.block(time.Now().Add(apierr.RetryAfter))
+		}
+
+		if !apierr.Temporary() || retry+1 >= m.retry.attempts {
+			return apierr
+		}
+
+		// long waits are left for the caller, i.e. for the next pull
+		delay := m.retry.delay(retry)
+		if apierr.RetryAfter > 0 {
+			if apierr.RetryAfter > m.retry.maxbackoff {
+				return apierr
+			}
+
+			delay = apierr.RetryAfter
+		}
+
+		metricRetries.Inc(apierr.Class.String())
+		m.log.Warn().Msgf("%s; retrying in %s", apierr.Error(), delay.Round(time.Millisecond).String())
+		time.Sleep(delay)
+	}
+}
+
+func (m *HttpClient) do(req *fasthttp.Request, rsp *fasthttp.Response) *ApiError {
+	if e := m.Do(req, rsp); e != nil {
+		return &ApiError{Class: ERROR_TRANSIENT, Message: e.Error()}
+	}
+
+	m.rate.update(&rsp.Header)
+
+	status := rsp.StatusCode()
+	if status == fasthttp.StatusNotModified || (status >= fasthttp.StatusOK && status < fasthttp.StatusMultipleChoices) {
+		return nil
+	}
+
+	return classifyResponse(rsp, time.Now())
+}
+
+func classifyResponse(rsp *fasthttp.Response, now time.Time) (apierr *ApiError) {
+	apierr = &ApiError{Status: rsp.StatusCode(), RetryAfter: parseRetryAfter(rsp.Header.Peek(fasthttp.HeaderRetryAfter), now)}
+
+	gherr := &GithubError{}
+	if e := easyjson.Unmarshal(rsp.Body(), gherr); e == nil {
+		apierr.Message = gherr.Message
+	}
+	if apierr.Message == "" {
+		apierr.Message = http.StatusText(apierr.Status)
+	}
+
+	message := strings.ToLower(apierr.Message)
+	switch {
+	case strings.Contains(message, "secondary rate limit"):
+		apierr.Class = ERROR_SECONDARY_RATE_LIMITED
+		if apierr.RetryAfter == 0 {
+			apierr.RetryAfter = secondaryRateLimitWait
+		}
+	case apierr.Status == fasthttp.StatusTooManyRequests,
+		apierr.Status == fasthttp.StatusForbidden && futils.UnsafeString(rsp.Header.Peek("X-RateLimit-Remaining")) == "0",
+		strings.Contains(message, "rate limit exceeded"):
+		apierr.Class = ERROR_RATE_LIMITED
+		if apierr.RetryAfter == 0 {
+			if reset, e := strconv.ParseInt(futils.UnsafeString(rsp.Header.Peek("X-RateLimit-Reset")), 10, 64); e == nil &&
+				time.Unix(reset, 0).After(now) {
+				apierr.RetryAfter = time.Unix(reset, 0).Sub(now)
+			}
+		}
+	case apierr.Status == fasthttp.StatusUnauthorized:
+		apierr.Class = ERROR_UNAUTHORIZED
+	case apierr.Status == fasthttp.StatusForbidden:
+		apierr.Class = ERROR_FORBIDDEN
+	case apierr.Status == fasthttp.StatusNotFound:
+		apierr.Class = ERROR_NOT_FOUND
+	case apierr.Status >= fasthttp.StatusInternalServerError:
+		apierr.Class = ERROR_TRANSIENT
+	default:
+		apierr.Class = ERROR_CLIENT
+	}
+
+	return
+}
+
+// parseRetryAfter accepts delay seconds and http dates
+func parseRetryAfter(value []byte, now time.Time) time.Duration {
+	if len(value) == 0 {
+		return 0
+	}
+
+	if seconds, e := strconv.Atoi(futils.UnsafeString(value)); e == nil && seconds > 0 {
+		return time.Duration(seconds) * time.Second
+	}
+
+	if date, e := http.ParseTime(string(value)); e == nil && date.After(now) {
+		return date.Sub(now)
+	}
+
+	return 0
+}
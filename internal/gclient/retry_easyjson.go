@@ -0,0 +1,85 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package gclient
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjsonD537cae8DecodeGithubComMindHunter86AsmasInternalGclient(in *jlexer.Lexer, out *GithubError) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "message":
+			out.Message = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjsonD537cae8EncodeGithubComMindHunter86AsmasInternalGclient(out *jwriter.Writer, in GithubError) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"message\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Message))
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GithubError) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjsonD537cae8EncodeGithubComMindHunter86AsmasInternalGclient(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GithubError) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjsonD537cae8EncodeGithubComMindHunter86AsmasInternalGclient(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GithubError) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjsonD537cae8DecodeGithubComMindHunter86AsmasInternalGclient(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GithubError) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjsonD537cae8DecodeGithubComMindHunter86AsmasInternalGclient(l, v)
+}
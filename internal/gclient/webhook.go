@@ -0,0 +1,53 @@
+package gclient
+
+import "strings"
+
+type (
+	//easyjson:json
+	GithubPushEvent struct {
+		Ref        string
+		Repository *GithubRepository `json:",omitempty"`
+		Commits    []*GithubCommit   `json:",omitempty"`
+		HeadCommit *GithubCommit     `json:"head_commit,omitempty"`
+	}
+	GithubRepository struct {
+		FullName string `json:"full_name"`
+	}
+	GithubCommit struct {
+		Id       string
+		Added    []string `json:",omitempty"`
+		Removed  []string `json:",omitempty"`
+		Modified []string `json:",omitempty"`
+	}
+)
+
+// github push events contain 20 commits at most, the rest ones are truncated
+const GithubPushEventMaxCommits = 20
+
+// Touches reports whether the push event has changes for the given path
+// or for any file in the given directory
+func (m *GithubPushEvent) Touches(path string) bool {
+	if len(m.Commits) >= GithubPushEventMaxCommits {
+		return true
+	}
+
+	for _, commit := range m.Commits {
+		if commit.touches(path) {
+			return true
+		}
+	}
+
+	return m.HeadCommit != nil && m.HeadCommit.touches(path)
+}
+
+func (m *GithubCommit) touches(path string) bool {
+	for _, files := range [][]string{m.Added, m.Removed, m.Modified} {
+		for _, file := range files {
+			if file == path || strings.HasPrefix(file, strings.TrimSuffix(path, "/")+"/") {
+				return true
+			}
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,359 @@
+// Code generated by easyjson for marshaling/unmarshaling. DO NOT EDIT.
+
+package gclient
+
+import (
+	json "encoding/json"
+	easyjson "github.com/mailru/easyjson"
+	jlexer "github.com/mailru/easyjson/jlexer"
+	jwriter "github.com/mailru/easyjson/jwriter"
+)
+
+// suppress unused package warning
+var (
+	_ *json.RawMessage
+	_ *jlexer.Lexer
+	_ *jwriter.Writer
+	_ easyjson.Marshaler
+)
+
+func easyjson3f91c269DecodeGithubComMindHunter86AsmasInternalGclient(in *jlexer.Lexer, out *GithubPushEvent) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "ref":
+			out.Ref = string(in.String())
+		case "repository":
+			if in.IsNull() {
+				in.Skip()
+				out.Repository = nil
+			} else {
+				if out.Repository == nil {
+					out.Repository = new(GithubRepository)
+				}
+				easyjson3f91c269DecodeGithubComMindHunter86AsmasInternalGclient1(in, out.Repository)
+			}
+		case "commits":
+			if in.IsNull() {
+				in.Skip()
+				out.Commits = nil
+			} else {
+				in.Delim('[')
+				if out.Commits == nil {
+					if !in.IsDelim(']') {
+						out.Commits = make([]*GithubCommit, 0, 8)
+					} else {
+						out.Commits = []*GithubCommit{}
+					}
+				} else {
+					out.Commits = (out.Commits)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v1 *GithubCommit
+					if in.IsNull() {
+						in.Skip()
+						v1 = nil
+					} else {
+						if v1 == nil {
+							v1 = new(GithubCommit)
+						}
+						easyjson3f91c269DecodeGithubComMindHunter86AsmasInternalGclient2(in, v1)
+					}
+					out.Commits = append(out.Commits, v1)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "head_commit":
+			if in.IsNull() {
+				in.Skip()
+				out.HeadCommit = nil
+			} else {
+				if out.HeadCommit == nil {
+					out.HeadCommit = new(GithubCommit)
+				}
+				easyjson3f91c269DecodeGithubComMindHunter86AsmasInternalGclient2(in, out.HeadCommit)
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson3f91c269EncodeGithubComMindHunter86AsmasInternalGclient(out *jwriter.Writer, in GithubPushEvent) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"ref\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Ref))
+	}
+	if in.Repository != nil {
+		const prefix string = ",\"repository\":"
+		out.RawString(prefix)
+		easyjson3f91c269EncodeGithubComMindHunter86AsmasInternalGclient1(out, *in.Repository)
+	}
+	if len(in.Commits) != 0 {
+		const prefix string = ",\"commits\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v2, v3 := range in.Commits {
+				if v2 > 0 {
+					out.RawByte(',')
+				}
+				if v3 == nil {
+					out.RawString("null")
+				} else {
+					easyjson3f91c269EncodeGithubComMindHunter86AsmasInternalGclient2(out, *v3)
+				}
+			}
+			out.RawByte(']')
+		}
+	}
+	if in.HeadCommit != nil {
+		const prefix string = ",\"head_commit\":"
+		out.RawString(prefix)
+		easyjson3f91c269EncodeGithubComMindHunter86AsmasInternalGclient2(out, *in.HeadCommit)
+	}
+	out.RawByte('}')
+}
+
+// MarshalJSON supports json.Marshaler interface
+func (v GithubPushEvent) MarshalJSON() ([]byte, error) {
+	w := jwriter.Writer{}
+	easyjson3f91c269EncodeGithubComMindHunter86AsmasInternalGclient(&w, v)
+	return w.Buffer.BuildBytes(), w.Error
+}
+
+// MarshalEasyJSON supports easyjson.Marshaler interface
+func (v GithubPushEvent) MarshalEasyJSON(w *jwriter.Writer) {
+	easyjson3f91c269EncodeGithubComMindHunter86AsmasInternalGclient(w, v)
+}
+
+// UnmarshalJSON supports json.Unmarshaler interface
+func (v *GithubPushEvent) UnmarshalJSON(data []byte) error {
+	r := jlexer.Lexer{Data: data}
+	easyjson3f91c269DecodeGithubComMindHunter86AsmasInternalGclient(&r, v)
+	return r.Error()
+}
+
+// UnmarshalEasyJSON supports easyjson.Unmarshaler interface
+func (v *GithubPushEvent) UnmarshalEasyJSON(l *jlexer.Lexer) {
+	easyjson3f91c269DecodeGithubComMindHunter86AsmasInternalGclient(l, v)
+}
+func easyjson3f91c269DecodeGithubComMindHunter86AsmasInternalGclient2(in *jlexer.Lexer, out *GithubCommit) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "id":
+			out.Id = string(in.String())
+		case "added":
+			if in.IsNull() {
+				in.Skip()
+				out.Added = nil
+			} else {
+				in.Delim('[')
+				if out.Added == nil {
+					if !in.IsDelim(']') {
+						out.Added = make([]string, 0, 4)
+					} else {
+						out.Added = []string{}
+					}
+				} else {
+					out.Added = (out.Added)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v4 string
+					v4 = string(in.String())
+					out.Added = append(out.Added, v4)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "removed":
+			if in.IsNull() {
+				in.Skip()
+				out.Removed = nil
+			} else {
+				in.Delim('[')
+				if out.Removed == nil {
+					if !in.IsDelim(']') {
+						out.Removed = make([]string, 0, 4)
+					} else {
+						out.Removed = []string{}
+					}
+				} else {
+					out.Removed = (out.Removed)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v5 string
+					v5 = string(in.String())
+					out.Removed = append(out.Removed, v5)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		case "modified":
+			if in.IsNull() {
+				in.Skip()
+				out.Modified = nil
+			} else {
+				in.Delim('[')
+				if out.Modified == nil {
+					if !in.IsDelim(']') {
+						out.Modified = make([]string, 0, 4)
+					} else {
+						out.Modified = []string{}
+					}
+				} else {
+					out.Modified = (out.Modified)[:0]
+				}
+				for !in.IsDelim(']') {
+					var v6 string
+					v6 = string(in.String())
+					out.Modified = append(out.Modified, v6)
+					in.WantComma()
+				}
+				in.Delim(']')
+			}
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson3f91c269EncodeGithubComMindHunter86AsmasInternalGclient2(out *jwriter.Writer, in GithubCommit) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"id\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.Id))
+	}
+	if len(in.Added) != 0 {
+		const prefix string = ",\"added\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v7, v8 := range in.Added {
+				if v7 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v8))
+			}
+			out.RawByte(']')
+		}
+	}
+	if len(in.Removed) != 0 {
+		const prefix string = ",\"removed\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v9, v10 := range in.Removed {
+				if v9 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v10))
+			}
+			out.RawByte(']')
+		}
+	}
+	if len(in.Modified) != 0 {
+		const prefix string = ",\"modified\":"
+		out.RawString(prefix)
+		{
+			out.RawByte('[')
+			for v11, v12 := range in.Modified {
+				if v11 > 0 {
+					out.RawByte(',')
+				}
+				out.String(string(v12))
+			}
+			out.RawByte(']')
+		}
+	}
+	out.RawByte('}')
+}
+func easyjson3f91c269DecodeGithubComMindHunter86AsmasInternalGclient1(in *jlexer.Lexer, out *GithubRepository) {
+	isTopLevel := in.IsStart()
+	if in.IsNull() {
+		if isTopLevel {
+			in.Consumed()
+		}
+		in.Skip()
+		return
+	}
+	in.Delim('{')
+	for !in.IsDelim('}') {
+		key := in.UnsafeFieldName(false)
+		in.WantColon()
+		if in.IsNull() {
+			in.Skip()
+			in.WantComma()
+			continue
+		}
+		switch key {
+		case "full_name":
+			out.FullName = string(in.String())
+		default:
+			in.SkipRecursive()
+		}
+		in.WantComma()
+	}
+	in.Delim('}')
+	if isTopLevel {
+		in.Consumed()
+	}
+}
+func easyjson3f91c269EncodeGithubComMindHunter86AsmasInternalGclient1(out *jwriter.Writer, in GithubRepository) {
+	out.RawByte('{')
+	first := true
+	_ = first
+	{
+		const prefix string = ",\"full_name\":"
+		out.RawString(prefix[1:])
+		out.String(string(in.FullName))
+	}
+	out.RawByte('}')
+}
@@ -0,0 +1,87 @@
+package lockout
+
+import (
+	"container/list"
+	"time"
+)
+
+// counterSet keeps counters of one kind ordered by the last failure, so the least
+// recently failed counter is evicted in constant time when the limit is reached
+type counterSet struct {
+	kind  string
+	items map[string]*counter
+	order *list.List
+}
+
+type counter struct {
+	key         string
+	failures    []time.Time
+	lockeduntil time.Time
+
+	elem *list.Element
+}
+
+func newCounterSet(kind string) *counterSet {
+	return &counterSet{kind: kind, items: make(map[string]*counter), order: list.New()}
+}
+
+func (m *counterSet) get(key string) *counter {
+	return m.items[key]
+}
+
+func (m *counterSet) len() int {
+	return len(m.items)
+}
+
+// touch returns the counter of the key moved to the front, it's created if missing
+func (m *counterSet) touch(key string) *counter {
+	if ctr, ok := m.items[key]; ok {
+		m.order.MoveToFront(ctr.elem)
+		return ctr
+	}
+
+	// keys may be backed by reusable request buffers, i.e. fiber query values
+	key = string(append([]byte(nil), key...))
+
+	ctr := &counter{key: key}
+	ctr.elem = m.order.PushFront(ctr)
+	m.items[key] = ctr
+
+	return ctr
+}
+
+// oldest returns the least recently failed counter or nil
+func (m *counterSet) oldest() *counter {
+	if elem := m.order.Back(); elem != nil {
+		return elem.Value.(*counter)
+	}
+
+	return nil
+}
+
+func (m *counterSet) remove(ctr *counter) {
+	m.order.Remove(ctr.elem)
+	delete(m.items, ctr.key)
+}
+
+//
+//
+//
+
+func (m *counter) remaining(now time.Time) time.Duration {
+	if m == nil || !m.lockeduntil.After(now) {
+		return 0
+	}
+
+	return m.lockeduntil.Sub(now)
+}
+
+// expire returns failures in the sliding window, they are sorted by time
+func (m *counter) expire(now time.Time, window time.Duration) []time.Time {
+	var i int
+	for i < len(m.failures) && now.Sub(m.failures[i]) > window {
+		i++
+	}
+
+	return m.failures[i:]
+}
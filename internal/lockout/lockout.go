@@ -0,0 +1,284 @@
+package lockout
+
+import (
+	"context"
+	"errors"
+	"net/netip"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/MindHunter86/asmas/internal/metrics"
+	"github.com/MindHunter86/asmas/internal/utils"
+	"github.com/rs/zerolog"
+	"github.com/urfave/cli/v2"
+)
+
+const (
+	KIND_IP       = "ip"
+	KIND_HOSTNAME = "hostname"
+)
+
+var (
+	metricLockouts = metrics.NewCounter("asmas_lockouts_total",
+		"Count of temporary lockouts after failed request signatures.", "kind")
+	metricEvictions = metrics.NewCounter("asmas_lockout_evictions_total",
+		"Count of lockout counters evicted by lockout-max-counters limit.", "kind")
+)
+
+// Lockout counts failed request signatures per client ip and per hostname in sliding
+// windows and temporarily locks out clients exceeded the thresholds; the hostname is
+// unauthenticated, so its lockout is alerting only: it's logged and counted in metrics,
+// failed requests get 429, but valid signatures are never blocked and only the ip
+// lockout protects from brute-force
+type Lockout struct {
+	window        time.Duration
+	duration      time.Duration
+	ipthreshold   int
+	hostthreshold int
+	maxcounters   int
+	allowlist     []netip.Prefix
+
+	mu    sync.Mutex
+	ips   *counterSet
+	hosts *counterSet
+
+	log  *zerolog.Logger
+	done func() <-chan struct{}
+}
+
+// Status is the counter state returned by the internal api
+type Status struct {
+	Kind        string     `json:"kind"`
+	Key         string     `json:"key"`
+	Failures    int        `json:"failures"`
+	LockedUntil *time.Time `json:"locked_until,omitempty"`
+}
+
+func NewLockout(c context.Context, cc *cli.Context) (_ *Lockout, e error) {
+	lockout := &Lockout{
+		window:        cc.Duration("lockout-window"),
+		duration:      cc.Duration("lockout-duration"),
+		ipthreshold:   cc.Int("lockout-ip-threshold"),
+		hostthreshold: cc.Int("lockout-hostname-threshold"),
+		maxcounters:   cc.Int("lockout-max-counters"),
+
+		ips:   newCounterSet(KIND_IP),
+		hosts: newCounterSet(KIND_HOSTNAME),
+
+		log:  c.Value(utils.CKeyLogger).(*zerolog.Logger),
+		done: c.Done,
+	}
+
+	for _, network := range cc.StringSlice("lockout-allowlist") {
+		var prefix netip.Prefix
+		if prefix, e = parsePrefix(network); e != nil {
+			return nil, errors.New("invalid lockout-allowlist item " + network + ", " + e.Error())
+		}
+
+		lockout.allowlist = append(lockout.allowlist, prefix)
+	}
+
+	// client ips are socket addresses without trusted proxies, see clientIP of the service
+	if lockout.ipthreshold > 0 && cc.String("http-trusted-proxies") == "" {
+		lockout.log.Warn().Msg("lockout-ip-threshold is enabled without http-trusted-proxies, " +
+			"all clients behind a reverse proxy share its ip and will be locked out together")
+	}
+
+	return lockout, e
+}
+
+// Bootstrap purges stale counters until the service is closed
+func (m *Lockout) Bootstrap() {
+	if m.window <= 0 {
+		<-m.done()
+		return
+	}
+
+	ticker := time.NewTicker(m.window)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-m.done():
+			return
+		case <-ticker.C:
+			actionWithLock(&m.mu, func() {
+				now := time.Now()
+				m.purge(m.ips, now)
+				m.purge(m.hosts, now)
+			})
+		}
+	}
+}
+
+// Check returns the remaining lockout time of the client ip, it's used before signature verification
+func (m *Lockout) Check(ip string) (retry time.Duration) {
+	if m.isAllowed(ip) {
+		return
+	}
+
+	actionWithLock(&m.mu, func() {
+		retry = m.ips.get(ip).remaining(time.Now())
+	})
+
+	return
+}
+
+// Fail registers failed signature verification, locks out exceeded clients and returns
+// the remaining lockout time of the client ip or the hostname for the failed request
+func (m *Lockout) Fail(ip, hostname string) (retry time.Duration) {
+	if m.isAllowed(ip) {
+		return
+	}
+
+	actionWithLock(&m.mu, func() {
+		now := time.Now()
+		m.fail(m.ips, ip, m.ipthreshold, now)
+		m.fail(m.hosts, hostname, m.hostthreshold, now)
+
+		for _, ctr := range []*counter{m.ips.get(ip), m.hosts.get(hostname)} {
+			if remaining := ctr.remaining(now); remaining > retry {
+				retry = remaining
+			}
+		}
+	})
+
+	return
+}
+
+// Statuses returns all counters with failures in the current window or active lockouts
+func (m *Lockout) Statuses() (statuses []*Status) {
+	statuses = make([]*Status, 0)
+
+	actionWithLock(&m.mu, func() {
+		now := time.Now()
+		for _, counters := range []*counterSet{m.ips, m.hosts} {
+			m.purge(counters, now)
+
+			for key, ctr := range counters.items {
+				status := &Status{Kind: counters.kind, Key: key, Failures: len(ctr.failures)}
+				if ctr.lockeduntil.After(now) {
+					lockeduntil := ctr.lockeduntil
+					status.LockedUntil = &lockeduntil
+				}
+
+				statuses = append(statuses, status)
+			}
+		}
+	})
+
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Kind != statuses[j].Kind {
+			return statuses[i].Kind < statuses[j].Kind
+		}
+		return statuses[i].Key < statuses[j].Key
+	})
+
+	return
+}
+
+// Clear removes counters of the given ip or hostname; empty key clears everything
+func (m *Lockout) Clear(key string) (cleared int) {
+	actionWithLock(&m.mu, func() {
+		for _, counters := range []*counterSet{m.ips, m.hosts} {
+			for k, ctr := range counters.items {
+				if key == "" || strings.EqualFold(k, key) {
+					counters.remove(ctr)
+					cleared++
+				}
+			}
+		}
+	})
+
+	m.log.Info().Msgf("lockout counters have been cleared by internal api, key %q, %d removed", key, cleared)
+	return
+}
+
+//
+//
+//
+
+func (m *Lockout) isAllowed(ip string) bool {
+	addr, e := netip.ParseAddr(ip)
+	if e != nil {
+		return false
+	}
+	addr = addr.Unmap()
+
+	for _, prefix := range m.allowlist {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (m *Lockout) fail(counters *counterSet, key string, threshold int, now time.Time) {
+	if threshold <= 0 || key == "" {
+		return
+	}
+
+	// the least recently failed counter is evicted, so the limit costs constant time
+	if m.maxcounters > 0 && counters.get(key) == nil && counters.len() >= m.maxcounters {
+		evicted := counters.oldest()
+		counters.remove(evicted)
+
+		metricEvictions.Inc(counters.kind)
+		m.log.Debug().Msgf("lockout counters limit %d has been reached, %s %s has been evicted",
+			m.maxcounters, counters.kind, evicted.key)
+	}
+
+	ctr := counters.touch(key)
+
+	// only the last threshold failures are needed for the lockout decision
+	if ctr.failures = append(ctr.expire(now, m.window), now); len(ctr.failures) > threshold {
+		ctr.failures = append(ctr.failures[:0], ctr.failures[len(ctr.failures)-threshold:]...)
+	}
+
+	if len(ctr.failures) < threshold || ctr.lockeduntil.After(now) {
+		return
+	}
+
+	ctr.lockeduntil = now.Add(m.duration)
+	metricLockouts.Inc(counters.kind)
+
+	m.log.Warn().Msgf("%s %s has been locked out for %s after %d failed signatures in %s",
+		counters.kind, key, m.duration.String(), len(ctr.failures), m.window.String())
+}
+
+func (m *Lockout) purge(counters *counterSet, now time.Time) {
+	for _, ctr := range counters.items {
+		if ctr.failures = ctr.expire(now, m.window); len(ctr.failures) == 0 && !ctr.lockeduntil.After(now) {
+			counters.remove(ctr)
+		}
+	}
+}
+
+func parsePrefix(network string) (_ netip.Prefix, e error) {
+	if !strings.Contains(network, "/") {
+		var addr netip.Addr
+		if addr, e = netip.ParseAddr(network); e != nil {
+			return
+		}
+
+		addr = addr.Unmap()
+		return netip.PrefixFrom(addr, addr.BitLen()), e
+	}
+
+	var prefix netip.Prefix
+	if prefix, e = netip.ParsePrefix(network); e != nil {
+		return
+	}
+
+	return prefix.Masked(), e
+}
+
+func actionWithLock(mu *sync.Mutex, action func()) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	action()
+}
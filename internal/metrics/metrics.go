@@ -0,0 +1,170 @@
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+type MetricType uint8
+
+const (
+	METRIC_COUNTER MetricType = iota
+	METRIC_GAUGE
+)
+
+func (m MetricType) String() string {
+	switch m {
+	case METRIC_COUNTER:
+		return "counter"
+	case METRIC_GAUGE:
+		return "gauge"
+	default:
+		return "untyped"
+	}
+}
+
+// Metric is a family of samples sharing name, help and label names;
+// samples are exposed in prometheus text format by WriteTo
+type Metric struct {
+	name   string
+	help   string
+	mtype  MetricType
+	labels []string
+
+	mu      sync.RWMutex
+	samples map[string]*sample
+}
+
+type sample struct {
+	values []string
+	value  float64
+}
+
+var (
+	mu       sync.RWMutex
+	registry = make(map[string]*Metric)
+)
+
+func NewCounter(name, help string, labels ...string) *Metric {
+	return register(&Metric{name: name, help: help, mtype: METRIC_COUNTER, labels: labels})
+}
+
+func NewGauge(name, help string, labels ...string) *Metric {
+	return register(&Metric{name: name, help: help, mtype: METRIC_GAUGE, labels: labels})
+}
+
+func (m *Metric) Inc(values ...string) {
+	m.Add(1, values...)
+}
+
+func (m *Metric) Add(delta float64, values ...string) {
+	m.update(values, func(s *sample) { s.value += delta })
+}
+
+func (m *Metric) Set(value float64, values ...string) {
+	m.update(values, func(s *sample) { s.value = value })
+}
+
+// Reset drops all samples; it's used for gauges with volatile label values
+func (m *Metric) Reset() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.samples = make(map[string]*sample)
+}
+
+// WriteTo writes all registered metrics in prometheus text exposition format
+func WriteTo(w io.Writer) (e error) {
+	mu.RLock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	mu.RUnlock()
+
+	sort.Strings(names)
+
+	buf := bufio.NewWriter(w)
+	for _, name := range names {
+		mu.RLock()
+		metric := registry[name]
+		mu.RUnlock()
+
+		metric.writeTo(buf)
+	}
+
+	return buf.Flush()
+}
+
+//
+//
+//
+
+func register(metric *Metric) *Metric {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if _, ok := registry[metric.name]; ok {
+		panic("BUG! metric " + metric.name + " has been already registered")
+	}
+
+	metric.samples = make(map[string]*sample)
+	registry[metric.name] = metric
+	return metric
+}
+
+func (m *Metric) update(values []string, action func(*sample)) {
+	if len(values) != len(m.labels) {
+		panic(fmt.Sprintf("BUG! metric %s has %d labels, but %d values given", m.name, len(m.labels), len(values)))
+	}
+
+	key := strings.Join(values, "\xff")
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	s, ok := m.samples[key]
+	if !ok {
+		s = &sample{values: append([]string(nil), values...)}
+		m.samples[key] = s
+	}
+
+	action(s)
+}
+
+func (m *Metric) writeTo(w *bufio.Writer) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.help)
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.mtype.String())
+
+	keys := make([]string, 0, len(m.samples))
+	for key := range m.samples {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	for _, key := range keys {
+		s := m.samples[key]
+
+		w.WriteString(m.name)
+		if len(m.labels) != 0 {
+			w.WriteByte('{')
+			for i, label := range m.labels {
+				if i != 0 {
+					w.WriteByte(',')
+				}
+				w.WriteString(label + "=" + strconv.Quote(s.values[i]))
+			}
+			w.WriteByte('}')
+		}
+
+		w.WriteString(" " + strconv.FormatFloat(s.value, 'g', -1, 64) + "\n")
+	}
+}
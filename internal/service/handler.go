@@ -1,14 +1,23 @@
 package service
 
 import (
+	"crypto/subtle"
 	"errors"
+	"math"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/MindHunter86/asmas/internal/audit"
 	"github.com/MindHunter86/asmas/internal/auth"
+	"github.com/MindHunter86/asmas/internal/gclient"
+	"github.com/MindHunter86/asmas/internal/lockout"
+	"github.com/MindHunter86/asmas/internal/metrics"
 	"github.com/MindHunter86/asmas/internal/system"
 	"github.com/MindHunter86/asmas/internal/utils"
 	"github.com/gofiber/fiber/v2"
 	futils "github.com/gofiber/fiber/v2/utils"
+	"github.com/mailru/easyjson"
 )
 
 func (*Service) fiberDefaultErrorHandler(c *fiber.Ctx, err error) error {
@@ -19,19 +28,17 @@ func (*Service) fiberDefaultErrorHandler(c *fiber.Ctx, err error) error {
 		return c.Context().Conn().Close()
 	}
 
-	// ? not profitable
-	ferr := AcquireFErr()
-	defer ReleaseFErr(ferr)
-
 	// parse fiber error
+	var ferr *fiber.Error
 	if !errors.As(err, &ferr) {
-		rdebugf(c, "undefined error caught %+v", ferr)
-		ferr.Code, ferr.Message = fiber.StatusInternalServerError, err.Error()
-		return ferr
+		rdebugf(c, "undefined error caught %+v", err)
+		ferr = fiber.NewError(fiber.StatusInternalServerError)
+	} else {
+		rdebugf(c, "fiber error caught %+v", ferr)
 	}
 
-	rdebugf(c, "fiber error caught %+v", ferr)
-	return ferr
+	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
+	return c.Status(ferr.Code).SendString(ferr.Message)
 }
 
 //
@@ -39,8 +46,10 @@ func (*Service) fiberDefaultErrorHandler(c *fiber.Ctx, err error) error {
 //
 
 const (
-	RegistrationArgHostname = "hostname"
-	RegistrationArgSign     = "sign"
+	RegistrationArgHostname  = "hostname"
+	RegistrationArgSign      = "sign"
+	RegistrationArgSignature = "signature"
+	RegistrationArgName      = "name"
 )
 
 // !!!! REQUEST VALIDATION
@@ -53,64 +62,258 @@ func middlewareAuthentification(c *fiber.Ctx) error {
 		rdebugf(c, "hostname : %s", hostname)
 
 		rlog(c).Error().Msg("decline request wo hostname argument")
+		raudit(c, &audit.Record{Event: audit.EVENT_AUTHN, Result: "missing hostname"})
 		return fiber.NewError(fiber.StatusBadRequest)
 	}
 	c.Locals(auth.LKeyHostname, hostname)
 
-	var sign string
-	if sign = c.Query(RegistrationArgSign); sign == "" {
+	sign, signature := c.Query(RegistrationArgSign), c.Query(RegistrationArgSignature)
+	if sign == "" && signature == "" {
 		rdebugf(c, "sign : %s", sign)
 
 		rlog(c).Error().Msg("decline request wo sign argument")
+		raudit(c, &audit.Record{Event: audit.EVENT_AUTHN, Result: "missing sign"})
 		return fiber.NewError(fiber.StatusBadRequest)
 	}
 
+	lservice := c.UserContext().Value(utils.CKeyLockout).(*lockout.Lockout)
+	if retry := lservice.Check(clientIP(c)); retry > 0 {
+		rlog(c).Warn().Msg("decline request from locked out client, retry after " + retry.Round(time.Second).String())
+		raudit(c, &audit.Record{Event: audit.EVENT_AUTHN, Result: "locked out"})
+
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		return fiber.NewError(fiber.StatusTooManyRequests)
+	}
+
 	aservice := c.UserContext().Value(utils.CKeyAuthService).(*auth.AuthService)
 
 	var payload []byte
-	payloadlen := len(c.IP()) + len(c.Request().String()) + len(hostname)
-	if payload = aservice.PrepareHMACMessage(payloadlen, c.IP(), c.Path(), hostname); payload == nil {
+	if payload = auth.RequestHMACMessage(c.IP(), c.Path(), hostname); payload == nil {
 		rdebugf(c, "chunks: %s | %s | %s", c.IP(), c.Path(), hostname)
 
 		rlog(c).Error().Msg("unexpected result while preparing message for sign verification")
 		return fiber.NewError(fiber.StatusInternalServerError)
 	}
 
-	if expect, ok := aservice.VerifyHMACSign(payload, futils.UnsafeBytes(sign)); !ok {
+	// ed25519 signature is preferred, it's verified with public keys of the requested entry;
+	// routes without the name param (i.e. whoami) take it from the query
+	name := c.Params("name", c.Query(RegistrationArgName))
+	if signature != "" {
+		ok, e := aservice.VerifyRequestSignature(name, hostname, payload, signature)
+		if e != nil {
+			rlog(c).Error().Msg(e.Error())
+			return fiber.NewError(fiber.StatusInternalServerError)
+		}
+
+		if !ok {
+			rdebugf(c, "chunks : %s | %s | %s", c.IP(), c.Path(), hostname)
+
+			rlog(c).Error().Msg("decline request with unverified ed25519 signature")
+			raudit(c, &audit.Record{Event: audit.EVENT_AUTHN, Method: audit.METHOD_ED25519,
+				Entry: name, Result: "invalid signature"})
+			return declineFailedSignature(c, lservice, hostname)
+		}
+
+		raudit(c, &audit.Record{Event: audit.EVENT_AUTHN, Method: audit.METHOD_ED25519,
+			Entry: name, Result: audit.RESULT_ALLOWED})
+		return c.Next()
+	}
+
+	if !aservice.VerifyHMACSign(payload, futils.UnsafeBytes(sign)) {
 		rdebugf(c, "chunks : %s | %s | %s", c.IP(), c.Path(), hostname)
-		rdebugf(c, "recevied sign %s, expect %s", sign, expect)
 
 		rlog(c).Error().Msg("decline request with unverified hmac sign")
-		return fiber.NewError(fiber.StatusInternalServerError)
+		raudit(c, &audit.Record{Event: audit.EVENT_AUTHN, Method: audit.METHOD_HMAC, Result: "invalid sign"})
+		return declineFailedSignature(c, lservice, hostname)
 	}
 
+	raudit(c, &audit.Record{Event: audit.EVENT_AUTHN, Method: audit.METHOD_HMAC, Result: audit.RESULT_ALLOWED})
 	return c.Next()
 }
 
-// Variables authorization with Github config
-func middlewareAuthorization(c *fiber.Ctx) error {
-	var name string
-	if name = c.Params("name"); name == "" {
-		rdebugf(c, "name : %s", name)
+// declineFailedSignature registers the failure in lockout counters; locked out clients
+// and hostnames receive 429 with retry-after, others receive 401
+func declineFailedSignature(c *fiber.Ctx, lservice *lockout.Lockout, hostname string) error {
+	if retry := lservice.Fail(clientIP(c), hostname); retry > 0 {
+		c.Set(fiber.HeaderRetryAfter, strconv.Itoa(int(math.Ceil(retry.Seconds()))))
+		return fiber.NewError(fiber.StatusTooManyRequests)
+	}
 
-		rlog(c).Error().Msg("decline request with invalid name param")
-		return fiber.NewError(fiber.StatusBadRequest)
+	return fiber.NewError(fiber.StatusUnauthorized)
+}
+
+const (
+	githubWebhookPath  = "/hooks/github"
+	internalPathPrefix = "/internal/"
+)
+
+func middlewareMethodRestriction(c *fiber.Ctx) error {
+	switch c.Method() {
+	case fiber.MethodGet, fiber.MethodHead:
+		return c.Next()
+	case fiber.MethodPost:
+		if c.Path() == githubWebhookPath || strings.HasPrefix(c.Path(), internalPathPrefix) {
+			return c.Next()
+		}
 	}
 
-	hostname := c.Locals(auth.LKeyHostname).(string)
+	return fiber.NewError(fiber.StatusMethodNotAllowed)
+}
+
+// Static secret authorization for internal api
+func middlewareInternalAuthorization(c *fiber.Ctx) error {
+	var secret string
+	if secret = gCli.String("http-internal-secret"); secret == "" {
+		return fiber.NewError(fiber.StatusNotFound)
+	}
+
+	if subtle.ConstantTimeCompare(c.Request().Header.Peek("x-internal-secret"), futils.UnsafeBytes(secret)) != 1 {
+		rlog(c).Error().Msg("decline internal api request with invalid secret")
+		return fiber.NewError(fiber.StatusForbidden)
+	}
+
+	return c.Next()
+}
+
+// Variables authorization with Github config
+func middlewareAuthorization(scope auth.Scope) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		var name string
+		if name = c.Params("name"); name == "" {
+			rdebugf(c, "name : %s", name)
+
+			rlog(c).Error().Msg("decline request with invalid name param")
+			return fiber.NewError(fiber.StatusBadRequest)
+		}
+
+		hostname := c.Locals(auth.LKeyHostname).(string)
+		aservice := c.UserContext().Value(utils.CKeyAuthService).(*auth.AuthService)
+
+		result, e := aservice.Authorize(name, hostname, clientIP(c), scope)
+		if e != nil {
+			rlog(c).Error().Msg(e.Error())
+			raudit(c, &audit.Record{Event: audit.EVENT_AUTHZ, Entry: name, PemType: string(scope), Result: "error"})
+			return fiber.NewError(fiber.StatusInternalServerError)
+		}
+		raudit(c, &audit.Record{Event: audit.EVENT_AUTHZ, Entry: name, PemType: string(scope), Result: result.String()})
+
+		switch result {
+		case auth.AUTHZ_ALLOWED:
+		case auth.AUTHZ_NETWORK_MISMATCH:
+			rdebugf(c, "hostname : %s ; ip : %s", hostname, clientIP(c))
+
+			rlog(c).Error().Msg("decline request from unauthorized network")
+			return fiber.NewError(fiber.StatusForbidden)
+		case auth.AUTHZ_GRANT_INACTIVE:
+			rdebugf(c, "hostname : %s", hostname)
+
+			rlog(c).Error().Msg("decline request from hostname with expired or not yet active grant")
+			return fiber.NewError(fiber.StatusForbidden)
+		case auth.AUTHZ_CONFIG_EXPIRED:
+			rlog(c).Error().Msg("decline private key request, authorization config is older than auth-config-max-age")
+			return fiber.NewError(fiber.StatusServiceUnavailable, result.String())
+		case auth.AUTHZ_SCOPE_DENIED:
+			rdebugf(c, "hostname : %s ; scope : %s", hostname, scope)
+
+			rlog(c).Error().Msgf("decline request without granted %s scope", scope)
+			return fiber.NewError(fiber.StatusForbidden, "scope "+string(scope)+" is not granted")
+		default:
+			rdebugf(c, "hostname : %s ; reason : %s", hostname, result.String())
+
+			rlog(c).Error().Msg("decline request from unauthorized hostname")
+			return fiber.NewError(fiber.StatusForbidden)
+		}
+
+		return c.Next()
+	}
+}
+
+func handleHealthz(c *fiber.Ctx) error {
 	aservice := c.UserContext().Value(utils.CKeyAuthService).(*auth.AuthService)
-	if ok, e := aservice.AuthorizeHostname(name, hostname); e != nil {
+	return c.Status(fiber.StatusOK).JSON(aservice.ConfigStatus())
+}
+
+func handleGetWhoami(c *fiber.Ctx) error {
+	aservice := c.UserContext().Value(utils.CKeyAuthService).(*auth.AuthService)
+	hostname := c.Locals(auth.LKeyHostname).(string)
+
+	info, e := aservice.Whoami(hostname, clientIP(c))
+	if e != nil {
 		rlog(c).Error().Msg(e.Error())
 		return fiber.NewError(fiber.StatusInternalServerError)
-	} else if !ok {
-		rdebugf(c, "hostname : %s", hostname)
+	}
+
+	return c.Status(fiber.StatusOK).JSON(info)
+}
 
-		rlog(c).Error().Msg("decline request from unauthorized hostname")
+func handleGetLockouts(c *fiber.Ctx) error {
+	lservice := c.UserContext().Value(utils.CKeyLockout).(*lockout.Lockout)
+	return c.Status(fiber.StatusOK).JSON(lservice.Statuses())
+}
+
+// handleClearLockouts removes counters of ip or hostname given in key argument, or all of them
+func handleClearLockouts(c *fiber.Ctx) error {
+	lservice := c.UserContext().Value(utils.CKeyLockout).(*lockout.Lockout)
+	return c.Status(fiber.StatusOK).JSON(fiber.Map{"cleared": lservice.Clear(c.Query("key"))})
+}
+
+func handleGetConfigDiffs(c *fiber.Ctx) error {
+	aservice := c.UserContext().Value(utils.CKeyAuthService).(*auth.AuthService)
+	return c.Status(fiber.StatusOK).JSON(aservice.ConfigDiffs())
+}
+
+func handleGetConfigProvenance(c *fiber.Ctx) error {
+	aservice := c.UserContext().Value(utils.CKeyAuthService).(*auth.AuthService)
+
+	provenance := aservice.ConfigProvenance()
+	if provenance == nil {
+		return fiber.NewError(fiber.StatusServiceUnavailable, "authorization config has not been applied yet")
+	}
+
+	return c.Status(fiber.StatusOK).JSON(provenance)
+}
+
+func handleGithubWebhook(c *fiber.Ctx) error {
+	aservice := c.UserContext().Value(utils.CKeyAuthService).(*auth.AuthService)
+	if !aservice.IsWebhookEnabled() {
+		return fiber.NewError(fiber.StatusNotFound)
+	}
+
+	if !aservice.VerifyWebhookSign(c.Body(), c.Get("X-Hub-Signature-256")) {
+		rlog(c).Error().Msg("decline github webhook with invalid signature")
 		return fiber.NewError(fiber.StatusForbidden)
 	}
 
-	return c.Next()
+	switch event := c.Get("X-GitHub-Event"); event {
+	case "ping":
+		return respondPlainWithStatus(c, fiber.StatusOK)
+	case "push":
+	default:
+		rlog(c).Debug().Msg("skip github webhook with unexpected event " + event)
+		return respondPlainWithStatus(c, fiber.StatusAccepted)
+	}
+
+	event := &gclient.GithubPushEvent{}
+	if e := easyjson.Unmarshal(c.Body(), event); e != nil {
+		rlog(c).Error().Msg("could not parse github push event, " + e.Error())
+		return fiber.NewError(fiber.StatusBadRequest)
+	}
 
+	if !aservice.IsConfigPush(event) {
+		rlog(c).Debug().Msg("skip github push event, authorization config has not been changed")
+		return respondPlainWithStatus(c, fiber.StatusAccepted)
+	}
+
+	rlog(c).Info().Msg("github push event with authorization config changes received, " +
+		c.Get("X-GitHub-Delivery"))
+	aservice.TriggerUpdate()
+
+	return respondPlainWithStatus(c, fiber.StatusAccepted)
+}
+
+func handleGetMetrics(c *fiber.Ctx) error {
+	c.Set(fiber.HeaderContentType, "text/plain; version=0.0.4; charset=utf-8")
+	return metrics.WriteTo(c)
 }
 
 func handleGetCertificate(c *fiber.Ctx) (e error) {
@@ -127,10 +330,33 @@ func handleGetCertificate(c *fiber.Ctx) (e error) {
 		rlog(c).Error().Msg("an error occurred while peeking certificate from system, " + e.Error())
 		return fiber.NewError(fiber.StatusInternalServerError)
 	}
+	rauditPem(c, name, auth.SCOPE_PUBLIC)
 
 	return respondPlainWithStatus(c, fiber.StatusOK)
 }
 
+func handleGetInfo(c *fiber.Ctx) (e error) {
+	var name string
+	if name = c.Params("name"); name == "" {
+		rdebugf(c, "hostname : %s", name)
+
+		rlog(c).Error().Msg("decline request with invalid domain param")
+		return fiber.NewError(fiber.StatusBadRequest)
+	}
+
+	sservice := c.UserContext().Value(utils.CKeySystem).(*system.System)
+
+	var info *system.CertificateInfo
+	if info, e = sservice.CertificateInfo(name); e != nil {
+		rlog(c).Error().Msg("an error occurred while parsing certificate from system, " + e.Error())
+		return fiber.NewError(fiber.StatusInternalServerError)
+	}
+	raudit(c, &audit.Record{Event: audit.EVENT_PEM, Entry: name, PemType: string(auth.SCOPE_INFO),
+		Serial: info.Serial, Result: audit.RESULT_SERVED})
+
+	return c.Status(fiber.StatusOK).JSON(info)
+}
+
 func handleGetPrivate(c *fiber.Ctx) (e error) {
 	var name string
 	if name = c.Params("name"); name == "" {
@@ -145,6 +371,7 @@ func handleGetPrivate(c *fiber.Ctx) (e error) {
 		rlog(c).Error().Msg("an error occurred while peeking certificate from system, " + e.Error())
 		return fiber.NewError(fiber.StatusInternalServerError)
 	}
+	rauditPem(c, name, auth.SCOPE_PRIVATE)
 
 	return respondPlainWithStatus(c, fiber.StatusOK)
 }
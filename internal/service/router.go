@@ -10,6 +10,7 @@ import (
 	"sync"
 	"time"
 
+	"github.com/MindHunter86/asmas/internal/auth"
 	"github.com/gofiber/fiber/v2"
 	"github.com/gofiber/fiber/v2/middleware/pprof"
 	"github.com/gofiber/fiber/v2/middleware/recover"
@@ -56,6 +57,9 @@ func (m *Service) fiberMiddlewareInitialization() {
 		}))
 	}
 
+	// GETOnly replacement; bodies are allowed for webhooks only
+	m.fb.Use(middlewareMethodRestriction)
+
 	// request id 3.0
 	m.fb.Use(func(c *fiber.Ctx) error {
 		c.Set("X-Request-Id", strconv.FormatUint(c.Context().ID(), 10))
@@ -128,19 +132,30 @@ func (m *Service) fiberMiddlewareInitialization() {
 func (m *Service) fiberRouterInitialization() {
 	//
 	// ASMAS health api
-	m.fb.Get("/healthz", func(c *fiber.Ctx) error {
-		return respondPlainWithStatus(c, fiber.StatusOK)
-	})
+	m.fb.Get("/healthz", handleHealthz)
+
+	//
+	// ASMAS hooks api
+	hooks := m.fb.Group("/hooks")
+	hooks.Post("/github", handleGithubWebhook)
 
 	//
 	// ASMAS inteernal api
-	// inter := m.fb.Group("/internal", nil)
+	inter := m.fb.Group("/internal", middlewareInternalAuthorization)
+	inter.Get("/metrics", handleGetMetrics)
+	inter.Get("/config/diffs", handleGetConfigDiffs)
+	inter.Get("/config/status", handleGetConfigProvenance)
+	inter.Get("/lockouts", handleGetLockouts)
+	inter.Post("/lockouts/clear", handleClearLockouts)
 
 	//
 	// ASMAS public v1 api
-	v1 := m.fb.Group("/v1", middlewareAuthentification)
-
-	certs := v1.Group("/certificates/:name", middlewareAuthorization)
-	certs.Get("/public", handleGetCertificate)
-	certs.Get("/private", handleGetPrivate)
+	v1 := m.fb.Group("/v1")
+	v1.Get("/whoami", middlewareAuthentification, handleGetWhoami)
+
+	// authentication needs the entry name for ed25519 public keys lookup
+	certs := v1.Group("/certificates/:name", middlewareAuthentification)
+	certs.Get("/public", middlewareAuthorization(auth.SCOPE_PUBLIC), handleGetCertificate)
+	certs.Get("/private", middlewareAuthorization(auth.SCOPE_PRIVATE), handleGetPrivate)
+	certs.Get("/info", middlewareAuthorization(auth.SCOPE_INFO), handleGetInfo)
 }
@@ -11,7 +11,9 @@ import (
 	"sync"
 	"syscall"
 
+	"github.com/MindHunter86/asmas/internal/audit"
 	"github.com/MindHunter86/asmas/internal/auth"
+	"github.com/MindHunter86/asmas/internal/lockout"
 	"github.com/MindHunter86/asmas/internal/system"
 	"github.com/MindHunter86/asmas/internal/utils"
 	"github.com/gofiber/fiber/v2"
@@ -71,10 +73,12 @@ func NewService(c *cli.Context, l *zerolog.Logger, s io.Writer) *Service {
 
 		DisableDefaultContentType: true,
 
-		GETOnly: true,
+		// POST is allowed for github webhooks and internal api only, see middlewareMethodRestriction
+		GETOnly: false,
 		RequestMethods: []string{
 			fiber.MethodHead,
 			fiber.MethodGet,
+			fiber.MethodPost,
 		},
 
 		// JSONEncoder: easyjson.Marshal,
@@ -114,6 +118,24 @@ func (m *Service) Bootstrap() (e error) {
 	// BOOTSTRAP SECTION:
 	// ? write any subservice initialization block above the fiber server
 
+	// Audit Service
+	var auditor *audit.Auditor
+	if auditor, e = audit.NewAuditor(gCtx, gCli); e != nil {
+		gLog.Error().Msg("an error occurred while initializing audit service, " + e.Error())
+		return
+	}
+	gCtx = context.WithValue(gCtx, utils.CKeyAudit, auditor)
+	gofunc(&wg, auditor.Bootstrap)
+
+	// Brute-force Protection Service
+	var lockservice *lockout.Lockout
+	if lockservice, e = lockout.NewLockout(gCtx, gCli); e != nil {
+		gLog.Error().Msg("an error occurred while initializing lockout service, " + e.Error())
+		return
+	}
+	gCtx = context.WithValue(gCtx, utils.CKeyLockout, lockservice)
+	gofunc(&wg, lockservice.Bootstrap)
+
 	// System Maintain Service
 	sysservice := system.NewSystem(gCtx, gCli)
 	gCtx = context.WithValue(gCtx, utils.CKeySystem, sysservice)
@@ -1,8 +1,14 @@
 package service
 
 import (
+	"strconv"
 	"sync"
 
+	"github.com/MindHunter86/asmas/internal/audit"
+	"github.com/MindHunter86/asmas/internal/auth"
+	"github.com/MindHunter86/asmas/internal/system"
+	"github.com/MindHunter86/asmas/internal/utils"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
 )
@@ -39,6 +45,46 @@ func rdebugf(c *fiber.Ctx, format string, opts ...interface{}) {
 	rlog(c).Debug().Msgf(format, opts...)
 }
 
+// clientIP returns the proxy header value only if trusted proxies are defined;
+// fiber trusts the proxy header from anyone when trusted proxy check is disabled
+func clientIP(c *fiber.Ctx) string {
+	if !c.App().Config().EnableTrustedProxyCheck {
+		return c.Context().RemoteIP().String()
+	}
+
+	return c.IP()
+}
+
+// raudit fills request related fields of the record and writes it in the audit log
+func raudit(c *fiber.Ctx, record *audit.Record) {
+	record.RequestId = strconv.FormatUint(c.Context().ID(), 10)
+	record.ClientIP = clientIP(c)
+
+	if hostname, ok := c.Locals(auth.LKeyHostname).(string); ok {
+		record.Hostname = hostname
+	}
+
+	c.UserContext().Value(utils.CKeyAudit).(*audit.Auditor).Write(record)
+}
+
+// rauditPem writes the served pem record with the serial of the domain certificate
+func rauditPem(c *fiber.Ctx, name string, pemtype auth.Scope) {
+	if !c.UserContext().Value(utils.CKeyAudit).(*audit.Auditor).IsEnabled() {
+		return
+	}
+
+	record := &audit.Record{Event: audit.EVENT_PEM, Entry: name, PemType: string(pemtype), Result: audit.RESULT_SERVED}
+
+	sservice := c.UserContext().Value(utils.CKeySystem).(*system.System)
+	if info, e := sservice.CertificateInfo(name); e != nil {
+		rlog(c).Warn().Msg("could not get certificate serial for audit record, " + e.Error())
+	} else {
+		record.Serial = info.Serial
+	}
+
+	raudit(c, record)
+}
+
 func respondPlainWithStatus(c *fiber.Ctx, status int) error {
 	c.Set(fiber.HeaderContentType, fiber.MIMETextPlainCharsetUTF8)
 	return c.SendStatus(status)
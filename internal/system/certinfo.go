@@ -0,0 +1,47 @@
+package system
+
+import (
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"strings"
+	"time"
+)
+
+type CertificateInfo struct {
+	Subject   string    `json:"subject"`
+	Issuer    string    `json:"issuer"`
+	Serial    string    `json:"serial"`
+	DNSNames  []string  `json:"dns_names"`
+	NotBefore time.Time `json:"not_before"`
+	NotAfter  time.Time `json:"not_after"`
+}
+
+// CertificateInfo returns metadata of the leaf certificate of the given domain
+func (m *System) CertificateInfo(domain string) (_ *CertificateInfo, e error) {
+	bb := m.AcquireBuffer()
+	defer m.ReleaseBuffer(bb)
+
+	if e = m.readPemFile(domain, PEM_CERTIFICATE, bb); e != nil {
+		return
+	}
+
+	var block *pem.Block
+	if block, _ = pem.Decode(bb.Bytes()); block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("could not find certificate block in pem file of domain " + domain)
+	}
+
+	var cert *x509.Certificate
+	if cert, e = x509.ParseCertificate(block.Bytes); e != nil {
+		return
+	}
+
+	return &CertificateInfo{
+		Subject:   cert.Subject.String(),
+		Issuer:    cert.Issuer.String(),
+		Serial:    strings.ToLower(cert.SerialNumber.Text(16)),
+		DNSNames:  cert.DNSNames,
+		NotBefore: cert.NotBefore,
+		NotAfter:  cert.NotAfter,
+	}, e
+}
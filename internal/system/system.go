@@ -86,6 +86,22 @@ func (m *System) WritePemTo(domain string, ftype PemType, w io.Writer) (_ int, e
 		return
 	}
 
+	bb := m.AcquireBuffer()
+	defer m.ReleaseBuffer(bb)
+
+	if e = m.readPemFile(domain, ftype, bb); e != nil {
+		return
+	}
+
+	m.encodePayload(bb)
+	return w.Write(bb.Bytes())
+}
+
+//
+//
+//
+
+func (m *System) readPemFile(domain string, ftype PemType, bb *bytes.Buffer) (e error) {
 	if m.pemstorage.st == nil {
 		e = errors.New("pem storage is not ready yet")
 		return
@@ -103,27 +119,16 @@ func (m *System) WritePemTo(domain string, ftype PemType, w io.Writer) (_ int, e
 		return
 	}
 
-	bb := m.AcquireBuffer()
-	defer m.ReleaseBuffer(bb)
-
 	bb.Reset()
 	bb.Grow(int(pfile.Size))
 	for i := 0; i < int(pfile.Size); i++ {
 		bb.WriteByte(0)
 	}
 
-	if _, e = pfile.fd.ReadAt(bb.Bytes(), 0); e != nil {
-		return
-	}
-
-	m.encodePayload(bb)
-	return w.Write(bb.Bytes())
+	_, e = pfile.fd.ReadAt(bb.Bytes(), 0)
+	return
 }
 
-//
-//
-//
-
 func (m *System) closeMaintainedFiles() {
 	var e error
 
@@ -9,4 +9,6 @@ const (
 	CKeyErrorChan
 	CKeyAuthService
 	CKeySystem
+	CKeyAudit
+	CKeyLockout
 )